@@ -0,0 +1,152 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// A Checkpoint records how far a Scanner has consumed its input, so that
+// the scan can be resumed from there by Resume, for instance after a
+// restart.
+//
+// A checkpoint taken after a token has been processed makes the resumed
+// scan start with the token after it. How often each token is processed
+// across restarts depends on when the checkpoint is saved:
+//
+//   - Saved in the same atomic step as the results of processing the
+//     token, for instance in the same database transaction, each token is
+//     processed exactly once.
+//   - Saved after the results, a restart between the two steps processes
+//     the token again: tokens are processed at least once.
+//   - Saved before the results, a restart between the two steps loses the
+//     token: tokens are processed at most once.
+type Checkpoint struct {
+	// Pos is the position of the first byte of input not consumed
+	// by the scan. Its Offset is the absolute offset in the input.
+	Pos Position
+
+	// Token is the count of tokens returned before Pos.
+	Token int
+
+	// State is the state of the split function, saved if it was set with
+	// Scanner.SplitState. The client may also record its own state here;
+	// the Scanner stores it as is.
+	State []byte
+}
+
+// A SplitState is the state that a split function keeps from one token to
+// the next, which a Checkpoint must record for the scan to resume with the
+// same tokens. See Scanner.SplitState.
+type SplitState interface {
+	// SaveState returns an encoding of the state.
+	SaveState() []byte
+
+	// RestoreState sets the state to the one encoded by SaveState.
+	RestoreState(state []byte) error
+}
+
+// SplitState sets the state of the split function, to be saved in each
+// Checkpoint. If the Scanner was created by Resume, the state is first
+// restored from the Checkpoint it was given, and any error doing so is
+// returned. SplitState is to be called after Split, which clears it.
+//
+// The state is saved as the split function holds it when Checkpoint is
+// called, which is after the tokens read ahead by Peek or pushed back by
+// Unread, so checkpoints should be taken while no tokens are held.
+func (s *Scanner) SplitState(st SplitState) error {
+	s.splitState = st
+	state := s.resumed
+	s.resumed = nil
+	if state == nil {
+		return nil
+	}
+	return st.RestoreState(state)
+}
+
+// Checkpoint returns a checkpoint just past the input consumed for the
+// current token, or for the last token returned by Next if it has been
+// pushed back by Unread. Tokens read ahead by Peek are not counted as
+// consumed. If the current token is a chunk of a larger one, the rest of
+// the token is resumed as a new token.
+func (s *Scanner) Checkpoint() Checkpoint {
+	cp := Checkpoint{Pos: s.consumed, Token: s.index}
+	if s.splitState != nil {
+		cp.State = s.splitState.SaveState()
+	}
+	return cp
+}
+
+// Resume returns a new Scanner to read from rs, which it seeks to the
+// offset of cp. The scan goes on as if from the Scanner that took cp: the
+// positions and token counts of the new Scanner follow on from those of
+// cp. The split function defaults to SplitLines. The state of the split
+// function in cp is restored by SplitState.
+func Resume(rs io.ReadSeeker, cp Checkpoint) (*Scanner, error) {
+	if _, err := rs.Seek(cp.Pos.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	s := New(rs)
+	s.pos = cp.Pos
+	s.consumed = cp.Pos
+	s.tokens = cp.Token
+	s.index = cp.Token
+	s.resumed = cp.State
+	return s, nil
+}
+
+// checkpointVersion is the version of the encoding of a Checkpoint.
+const checkpointVersion = 1
+
+// errCheckpoint is returned when decoding an invalid Checkpoint.
+var errCheckpoint = errors.New("scanner: invalid Checkpoint encoding")
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (cp Checkpoint) MarshalBinary() ([]byte, error) {
+	b := []byte{checkpointVersion}
+	b = binary.AppendVarint(b, cp.Pos.Offset)
+	b = binary.AppendVarint(b, int64(cp.Pos.Line))
+	b = binary.AppendVarint(b, int64(cp.Pos.Column))
+	b = binary.AppendVarint(b, int64(cp.Token))
+	b = binary.AppendUvarint(b, uint64(len(cp.State)))
+	return append(b, cp.State...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (cp *Checkpoint) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != checkpointVersion {
+		return errCheckpoint
+	}
+	data = data[1:]
+
+	var vals [4]int64
+	for i := range vals {
+		v, n := binary.Varint(data)
+		if n <= 0 {
+			return errCheckpoint
+		}
+		vals[i] = v
+		data = data[n:]
+	}
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size != uint64(len(data)-n) {
+		return errCheckpoint
+	}
+
+	*cp = Checkpoint{
+		Pos: Position{
+			Offset: vals[0],
+			Line:   int(vals[1]),
+			Column: int(vals[2]),
+		},
+		Token: int(vals[3]),
+	}
+	if size > 0 {
+		cp.State = append([]byte{}, data[n:]...)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+type posToken struct {
+	pos   Position
+	token string
+}
+
+// scanFrom returns the remaining tokens of sc with their positions.
+func scanFrom(sc *Scanner) []posToken {
+	var tokens []posToken
+	for sc.Next() {
+		tokens = append(tokens, posToken{sc.Pos(), sc.Text()})
+	}
+	return tokens
+}
+
+// Test that a scan resumed from a checkpoint returns the tokens after the
+// one it was taken at, once each, at the same positions.
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		text := randomText(rng, rng.Intn(500))
+		for _, split := range []SplitFunc{SplitLines, SplitWords} {
+			sc := New(strings.NewReader(text))
+			sc.Split(split)
+			all := scanFrom(sc)
+
+			for k := 0; k <= len(all); k++ {
+				sc := New(strings.NewReader(text))
+				sc.Split(split)
+				for j := 0; j < k; j++ {
+					sc.Next()
+				}
+				switch rng.Intn(3) {
+				case 1:
+					// Tokens peeked at are not consumed.
+					sc.PeekN(2)
+				case 2:
+					// Nor is a token pushed back.
+					if sc.Next() {
+						sc.Unread()
+					}
+				}
+
+				cp := sc.Checkpoint()
+				if cp.Token != k {
+					t.Fatalf("%d: Token = %d; want %d", i, cp.Token, k)
+				}
+				cp.State = []byte("state")
+				b, err := cp.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+				var cp2 Checkpoint
+				if err := cp2.UnmarshalBinary(b); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(cp, cp2) {
+					t.Fatalf("%d: decoded %+v; want %+v", i, cp2, cp)
+				}
+
+				rs, err := Resume(strings.NewReader(text), cp2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rs.Split(split)
+				if got, want := scanFrom(rs), all[k:]; !slices.Equal(got, want) {
+					t.Fatalf("%d: resumed after %d tokens, got %v; want %v", i, k, got, want)
+				}
+				if err := rs.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if got := rs.Checkpoint().Token; got != len(all) {
+					t.Fatalf("%d: final Token = %d; want %d", i, got, len(all))
+				}
+			}
+		}
+	}
+}
+
+// headerSplit splits the first three lines of its input as lines and the
+// rest as words, keeping the count of lines split as its state.
+type headerSplit struct {
+	lines int
+}
+
+func (h *headerSplit) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if h.lines >= 3 {
+		return SplitWords(data, atEOF)
+	}
+	advance, token, err = SplitLines(data, atEOF)
+	if token != nil {
+		h.lines++
+	}
+	return advance, token, err
+}
+
+func (h *headerSplit) SaveState() []byte { return []byte{byte(h.lines)} }
+
+func (h *headerSplit) RestoreState(state []byte) error {
+	if len(state) != 1 {
+		return errors.New("bad headerSplit state")
+	}
+	h.lines = int(state[0])
+	return nil
+}
+
+// Test that the state of the split function is saved in a checkpoint and
+// restored when the scan is resumed.
+func TestCheckpointSplitState(t *testing.T) {
+	t.Parallel()
+
+	const text = "a b\nc d\ne f\ng h\ni j\n"
+	sc := New(strings.NewReader(text))
+	sc.Split(new(headerSplit).Split)
+	all := scanFrom(sc)
+	if len(all) != 7 {
+		t.Fatalf("got %d tokens; want 7", len(all))
+	}
+
+	for k := 0; k <= len(all); k++ {
+		var h headerSplit
+		sc := New(strings.NewReader(text))
+		sc.Split(h.Split)
+		if err := sc.SplitState(&h); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < k; j++ {
+			sc.Next()
+		}
+		cp := sc.Checkpoint()
+		if want := []byte{byte(min(k, 3))}; !slices.Equal(cp.State, want) {
+			t.Fatalf("%d: State = %v; want %v", k, cp.State, want)
+		}
+
+		var h2 headerSplit
+		rs, err := Resume(strings.NewReader(text), cp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Split(h2.Split)
+		if err := rs.SplitState(&h2); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := scanFrom(rs), all[k:]; !slices.Equal(got, want) {
+			t.Fatalf("resumed after %d tokens, got %v; want %v", k, got, want)
+		}
+	}
+
+	rs, err := Resume(strings.NewReader(text), Checkpoint{State: []byte("bad")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.SplitState(new(headerSplit)); err == nil {
+		t.Error("SplitState restored a bad state")
+	}
+}
+
+func TestCheckpointUnmarshalError(t *testing.T) {
+	b, _ := Checkpoint{Pos: Position{Offset: 300, Line: 2, Column: 5}, Token: 1}.MarshalBinary()
+	for _, data := range [][]byte{nil, {0}, b[:len(b)-1], append(b, 0)} {
+		var cp Checkpoint
+		if err := cp.UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary(%v) succeeded", data)
+		}
+	}
+}
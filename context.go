@@ -0,0 +1,128 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// NewContext returns a new Scanner to read from r whose Next method
+// gives up when ctx is done. See NextContext.
+func NewContext(ctx context.Context, r io.Reader) *Scanner {
+	s := New(r)
+	s.ctx = ctx
+	return s
+}
+
+// NextContext is like Next, but it returns false promptly once ctx is
+// done, even if it is blocked reading from the underlying reader, and
+// Err then returns ctx.Err(). Unlike other errors, this does not stop
+// the scan: the data read so far is kept, and a later call to Next or
+// NextContext resumes where this one left off.
+//
+// If the reader has a SetReadDeadline method, as net.Conn and *os.File
+// do, a blocked read is interrupted by setting a deadline in the past;
+// any read deadline set by the client is cleared. Otherwise the read is
+// left running in the background and its result is collected by the
+// next call to Next or NextContext.
+func (s *Scanner) NextContext(ctx context.Context) bool {
+	return s.next(ctx)
+}
+
+// readResult is the result of a read done in the background.
+type readResult struct {
+	buf []byte
+	n   int
+	err error
+}
+
+// deadliner is implemented by readers whose blocked reads can be
+// interrupted with a deadline, such as net.Conn and *os.File.
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// aLongTimeAgo is a non-zero time, far in the past, used to make
+// blocked reads fail immediately.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// read reads from the underlying reader into p. If ctx is done before the
+// read completes, it returns the context's error as cerr instead.
+func (s *Scanner) read(ctx context.Context, p []byte) (n int, err, cerr error) {
+	if s.pending == nil && (ctx == nil || ctx.Done() == nil) {
+		n, err = s.r.Read(p)
+		return n, err, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	if s.pending == nil {
+		if d, ok := s.r.(deadliner); ok && d.SetReadDeadline(time.Time{}) == nil {
+			return s.readDeadline(ctx, d, p)
+		}
+		s.readBackground(len(p))
+	}
+
+	select {
+	case res := <-s.pending:
+		s.pending = nil
+		s.spare = res.buf
+		if res.n < 0 || res.n > len(res.buf) {
+			return -1, res.err, nil
+		}
+		return copy(p, res.buf[:res.n]), res.err, nil
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+// readDeadline reads into p from d, the underlying reader, interrupting
+// the read with a deadline when ctx is done.
+func (s *Scanner) readDeadline(ctx context.Context, d deadliner, p []byte) (n int, err, cerr error) {
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		d.SetReadDeadline(aLongTimeAgo)
+		close(interrupted)
+	})
+
+	n, err = s.r.Read(p)
+	if stop() {
+		return n, err, nil
+	}
+
+	<-interrupted
+	d.SetReadDeadline(time.Time{})
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, nil, ctx.Err()
+	}
+	return n, err, nil
+}
+
+// readBackground starts a read of up to n bytes from the underlying reader
+// in a new goroutine, delivering its result to s.pending.
+func (s *Scanner) readBackground(n int) {
+	buf := s.spare
+	if cap(buf) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	s.spare = nil
+
+	ch := make(chan readResult, 1)
+	s.pending = ch
+	go func(r io.Reader) {
+		n, err := r.Read(buf)
+		ch <- readResult{buf, n, err}
+	}(s.r)
+}
@@ -0,0 +1,143 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// testCancel scans lines from r, which is fed by w, cancelling a Next
+// that is blocked in the middle of a line and then resuming the scan.
+func testCancel(t *testing.T, r io.Reader, w io.WriteCloser) {
+	go func() {
+		io.WriteString(w, "one\ntw")
+	}()
+
+	sc := New(r)
+	if !sc.Next() || sc.Text() != "one" {
+		t.Fatalf("got %q, %v; want \"one\"", sc.Text(), sc.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if sc.NextContext(ctx) {
+		t.Fatalf("NextContext returned %q; want cancellation", sc.Text())
+	}
+	if err := sc.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Err = %v; want %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if sc.NextContext(ctx) {
+		t.Fatalf("NextContext returned %q; want cancellation", sc.Text())
+	}
+	if err := sc.Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Err = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	go func() {
+		io.WriteString(w, "o\nthree\n")
+		w.Close()
+	}()
+
+	var got []string
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if len(got) != 2 || got[0] != "two" || got[1] != "three" {
+		t.Errorf("got %q after resuming; want [two three]", got)
+	}
+	if err := sc.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test cancellation of a reader that is read in the background.
+func TestCancelBackgroundRead(t *testing.T) {
+	t.Parallel()
+
+	r, w := io.Pipe()
+	testCancel(t, r, w)
+}
+
+// Test cancellation of a reader that supports read deadlines.
+func TestCancelDeadlineRead(t *testing.T) {
+	t.Parallel()
+
+	r, w := net.Pipe()
+	defer r.Close()
+	testCancel(t, r, w)
+}
+
+// Test that a Scanner created by NewContext uses its context.
+func TestNewContext(t *testing.T) {
+	t.Parallel()
+
+	r, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sc := NewContext(ctx, r)
+	if sc.Next() {
+		t.Fatalf("Next returned %q; want cancellation", sc.Text())
+	}
+	if err := sc.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Err = %v; want %v", err, context.Canceled)
+	}
+}
+
+// Test that Reader returns all the data of a read left running in the
+// background by a cancelled NextContext, even if it is read in small
+// pieces.
+func TestCancelReader(t *testing.T) {
+	t.Parallel()
+
+	r, w := io.Pipe()
+	go io.WriteString(w, "one\n")
+
+	sc := New(r)
+	if !sc.Next() || sc.Text() != "one" {
+		t.Fatalf("got %q, %v; want \"one\"", sc.Text(), sc.Err())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if sc.NextContext(ctx) {
+		t.Fatalf("NextContext returned %q; want cancellation", sc.Text())
+	}
+
+	const rest = "0123456789ABCDEF"
+	go func() {
+		io.WriteString(w, rest)
+		w.Close()
+	}()
+
+	var got []byte
+	rd := sc.Reader()
+	p := make([]byte, 4)
+	for {
+		n, err := rd.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(got) != rest {
+		t.Errorf("Reader returned %q; want %q", got, rest)
+	}
+}
@@ -26,6 +26,19 @@ func ExampleScanner_lines() {
 	}
 }
 
+// The simplest use of a Scanner as an iterator, to read standard input
+// as a set of lines.
+func ExampleScanner_All() {
+	sc := scanner.New(os.Stdin)
+	for line := range sc.All() {
+		fmt.Printf("%s\n", line)
+	}
+
+	if err := sc.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "reading standard input:", err)
+	}
+}
+
 // Return the most recent call to Scan as a []byte.
 func ExampleScanner_Bytes() {
 	sc := scanner.New(strings.NewReader("gopher"))
@@ -64,6 +77,31 @@ func ExampleScanner_words() {
 	// Output: 15
 }
 
+// Use the Strings iterator to implement a simple word-count utility
+// that also reports the longest word.
+func ExampleScanner_Strings() {
+	// An artificial input source.
+	const input = "Now is the winter of our discontent,\nMade glorious summer by this sun of York.\n"
+
+	sc := scanner.New(strings.NewReader(input))
+	sc.Split(scanner.SplitWords)
+
+	count := 0
+	longest := ""
+	for word := range sc.Strings() {
+		count++
+		if len(word) > len(longest) {
+			longest = word
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "reading input:", err)
+	}
+	fmt.Println(count, longest)
+	// Output: 15 discontent,
+}
+
 // Use a Scanner with a custom split function (built by wrapping ScanWords) to validate
 // 32-bit decimal input.
 func ExampleScanner_custom() {
@@ -97,6 +135,51 @@ func ExampleScanner_custom() {
 	// Invalid input: strconv.ParseInt: parsing "1234567901234567890": value out of range
 }
 
+// Change the split function partway through the input, to read a
+// line-delimited header followed by a body whose length the header gives.
+func ExampleScanner_Split() {
+	const input = "Subject: greeting\nLength: 13\n\nHello, world!Trailing line\n"
+
+	sc := scanner.New(strings.NewReader(input))
+
+	// Read header lines up to the first empty line.
+	length := 0
+	for sc.Next() && sc.Text() != "" {
+		name, value, _ := strings.Cut(sc.Text(), ": ")
+		fmt.Printf("%s=%q\n", name, value)
+		if name == "Length" {
+			length, _ = strconv.Atoi(value)
+		}
+	}
+
+	// Read the body as a single token of the given length.
+	sc.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(data) < length && !atEOF {
+			return 0, nil, nil
+		}
+		n := min(length, len(data))
+		return n, data[:n], nil
+	})
+	if sc.Next() {
+		fmt.Printf("body=%q\n", sc.Text())
+	}
+
+	// Go back to lines for the rest.
+	sc.Split(scanner.SplitLines)
+	for sc.Next() {
+		fmt.Printf("line=%q\n", sc.Text())
+	}
+
+	if err := sc.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "reading input:", err)
+	}
+	// Output:
+	// Subject="greeting"
+	// Length="13"
+	// body="Hello, world!"
+	// line="Trailing line"
+}
+
 // Use a Scanner with a custom split function to parse a comma-separated
 // list with an empty final value.
 func ExampleScanner_emptyFinalToken() {
@@ -133,3 +216,39 @@ func ExampleScanner_emptyFinalToken() {
 	}
 	// Output: "1" "2" "3" "4" ""
 }
+
+// Print the last lines of the input, like tail -n 2.
+func ExampleReverseScanner() {
+	const input = "first\nsecond\nthird\nfourth\n"
+	sc := scanner.NewReverse(strings.NewReader(input), int64(len(input)))
+
+	var lines []string
+	for len(lines) < 2 && sc.Next() {
+		lines = append(lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "reading input:", err)
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		fmt.Println(lines[i])
+	}
+	// Output:
+	// third
+	// fourth
+}
+
+// Use SplitOn to read records separated by blank lines.
+func ExampleSplitOn() {
+	const input = "From: a\r\nTo: b\r\n\r\nFrom: c\r\nTo: d\r\n\r\n"
+	sc := scanner.New(strings.NewReader(input))
+	sc.Split(scanner.SplitOn([]byte("\r\n\r\n")))
+	for sc.Next() {
+		fmt.Printf("%q\n", sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "reading input:", err)
+	}
+	// Output:
+	// "From: a\r\nTo: b"
+	// "From: c\r\nTo: d"
+}
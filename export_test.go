@@ -29,3 +29,13 @@ func (s *Scanner) MaxTokenSize(n int) {
 func (s *Scanner) ErrOrEOF() error {
 	return s.err
 }
+
+func (p *Parallel) Shards(n int) {
+	p.shards = n
+}
+
+// SplitOnCounting is SplitOn, adding to *searched the number of bytes
+// searched for sep.
+func SplitOnCounting(sep []byte, searched *int, opts ...SplitOption) SplitFunc {
+	return splitOn(sep, searched, opts)
+}
@@ -0,0 +1,81 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"io"
+	"os"
+)
+
+// NewFile returns a new Scanner to read f from its current offset.
+//
+// If f is a regular file and the operating system supports it, the file
+// is memory-mapped and scanned like NewBytes: no data is copied, there is
+// no maximum token size, and tokens stay valid until Close is called
+// rather than only until the next call to Next. The file must not be
+// truncated while it is mapped. The mapping is read-only, so the split
+// function must not modify its data, as with NewString: writing to it
+// crashes the program.
+//
+// Otherwise, for instance if f is a pipe, the Scanner reads from f as if
+// created by New. Mapped reports which is the case.
+func NewFile(f *os.File) (*Scanner, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Files such as those in /proc report a size of zero but have contents,
+	// so only a non-empty regular file is mapped.
+	size := fi.Size()
+	if !fi.Mode().IsRegular() || size == 0 || size != int64(int(size)) {
+		return New(f), nil
+	}
+
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if off >= size {
+		return New(f), nil
+	}
+
+	data, err := mmap(f, int(size))
+	if err != nil {
+		return New(f), nil
+	}
+
+	s := NewBytes(data[off:])
+	s.mapping = data
+	return s, nil
+}
+
+// Mapped reports whether the Scanner reads from a file memory-mapped by NewFile.
+func (s *Scanner) Mapped() bool { return s.mapping != nil }
+
+// Close releases the memory mapping of a Scanner created by NewFile, after
+// which its tokens are no longer valid and it returns no more tokens.
+// It does not close the file. For a Scanner set to ReadAhead, Close stops
+// the goroutine reading ahead, after which the Scanner returns no more
+// tokens. For other Scanners, Close does nothing.
+func (s *Scanner) Close() error {
+	if s.prefetch != nil {
+		s.prefetch.stop()
+		s.done = true
+	}
+	if s.mapping == nil {
+		return nil
+	}
+
+	err := munmap(s.mapping)
+	s.mapping = nil
+	s.buf = nil
+	s.token = nil
+	s.ahead = nil
+	s.start = 0
+	s.end = 0
+	s.done = true
+	return err
+}
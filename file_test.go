@@ -0,0 +1,106 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// Test that a memory-mapped file is scanned with tokens that stay valid.
+func TestNewFileMapped(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, strings.Repeat(string(rune('a'+i%26)), i%300))
+	}
+	text := strings.Join(lines, "\n")
+
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name, []byte("skipped\n"+text), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Seek(int64(len("skipped\n")), io.SeekStart)
+
+	sc, err := NewFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS == "linux" && !sc.Mapped() {
+		t.Error("regular file was not mapped")
+	}
+
+	var tokens [][]byte
+	for sc.Next() {
+		tokens = append(tokens, sc.Bytes())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != len(tokens) {
+		t.Fatalf("got %d lines; want %d", len(tokens), len(lines))
+	}
+	if sc.Mapped() {
+		for i, tok := range tokens {
+			if lines[i] != string(tok) {
+				t.Fatalf("%d: token changed to %.20q; want %.20q", i, tok, lines[i])
+			}
+		}
+	}
+
+	if err := sc.Close(); err != nil {
+		t.Error(err)
+	}
+	if sc.Mapped() || sc.Next() {
+		t.Error("Scanner still usable after Close")
+	}
+}
+
+// Test that a file that cannot be mapped is read instead.
+func TestNewFilePipe(t *testing.T) {
+	t.Parallel()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	go func() {
+		w.WriteString("one\ntwo\n")
+		w.Close()
+	}()
+
+	sc, err := NewFile(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	if sc.Mapped() {
+		t.Error("pipe was mapped")
+	}
+
+	var got []string
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "one two"; want != strings.Join(got, " ") {
+		t.Errorf("got %q; want %q", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultPoll is the default interval at which a Follower checks a file
+// for new data.
+const DefaultPoll = 250 * time.Millisecond
+
+// A Follower scans a file that is still being written, like tail -F.
+// Where a Scanner would stop at the end of the file, a Follower waits for
+// the file to grow, so Next blocks until another token is complete: a
+// final line is not returned until its newline arrives.
+//
+// The Follower also notices when the file is truncated, and starts again
+// from its beginning, dropping any unterminated token read before, and
+// when it is rotated, that is, when its path names a new file, and goes on
+// with the new file once the old one has no more data. A token left
+// unterminated at the end of the old file is joined with the start of the
+// new one.
+//
+// Truncation is checked for only once all the data has been read, at each
+// poll, and is noticed by the file being shorter than what was read or by
+// its first bytes having changed. A file truncated and written again past
+// the offset read, as by logrotate's copytruncate, is thus missed if it
+// starts with the same bytes as before, as when each log begins with the
+// same header: the Follower then reads on from the old offset.
+//
+// Next blocks until the context given to Follow is done, after which it
+// returns false and Err returns the context's error. NextContext can be
+// used to wait with another context.
+type Follower struct {
+	*Scanner
+	r *followReader
+}
+
+// Follow returns a new Follower to scan the file named by path from its
+// beginning. The split function defaults to SplitLines.
+func Follow(ctx context.Context, path string) (*Follower, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r := &followReader{
+		path: path,
+		f:    f,
+		fi:   fi,
+		poll: DefaultPoll,
+		kick: make(chan struct{}, 1),
+	}
+	return &Follower{Scanner: NewContext(ctx, r), r: r}, nil
+}
+
+// Poll sets the interval at which the Follower checks the file for new
+// data, truncation and rotation.
+func (f *Follower) Poll(d time.Duration) {
+	f.r.poll = d
+}
+
+// Close closes the file being followed. It must not be called while Next
+// is blocked; cancel the context given to Follow to stop it first.
+func (f *Follower) Close() error {
+	return f.r.close()
+}
+
+// followReader reads a file, waiting for it to grow at its end.
+// Its SetReadDeadline method lets a Scanner interrupt the wait.
+type followReader struct {
+	path string
+	f    *os.File    // The file being read.
+	fi   os.FileInfo // Information about f, to detect rotation.
+	off  int64       // Offset of the next read in f.
+	head []byte      // The first bytes of f, to detect truncation.
+	next *os.File    // The file that replaced f, once f is drained.
+	poll time.Duration
+
+	mu       sync.Mutex
+	deadline time.Time     // Deadline set by SetReadDeadline.
+	kick     chan struct{} // Signals a change of deadline.
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if r.f == nil {
+			return 0, os.ErrClosed
+		}
+		n, err := r.f.Read(p)
+		if r.off < headSize {
+			r.head = append(r.head, p[:min(n, headSize-int(r.off))]...)
+		}
+		r.off += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		if r.next != nil {
+			// The old file is drained; go on with the new one.
+			r.f.Close()
+			r.f, r.next, r.off, r.head = r.next, nil, 0, r.head[:0]
+			if r.fi, err = r.f.Stat(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		changed, err := r.check()
+		if err != nil {
+			return 0, err
+		}
+		if !changed {
+			if err := r.wait(); err != nil {
+				return 0, err
+			}
+			// Check again before reading, in case the file was truncated
+			// and grew past r.off in the meantime.
+			if _, err := r.check(); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// headSize is the number of bytes at the start of a followed file
+// compared at each poll to detect truncation.
+const headSize = 64
+
+// check looks for truncation and rotation of the file. It reports whether
+// there may be new data to read right away.
+func (r *followReader) check() (bool, error) {
+	fi, err := r.f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if fi.Size() < r.off {
+		return false, r.truncate()
+	}
+	if len(r.head) > 0 {
+		buf := make([]byte, len(r.head))
+		n, err := r.f.ReadAt(buf, 0)
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		if !bytes.Equal(buf[:n], r.head) {
+			return false, r.truncate()
+		}
+	}
+
+	fi, err = os.Stat(r.path)
+	if err != nil || os.SameFile(fi, r.fi) {
+		// The path is unchanged, or missing in the middle of a rotation.
+		return false, nil
+	}
+	f, err := os.Open(r.path)
+	if err != nil {
+		return false, nil
+	}
+	// Read the old file once more, in case it grew before it was rotated.
+	r.next = f
+	return true, nil
+}
+
+// truncate starts reading the file again from its beginning, and returns
+// errTruncated.
+func (r *followReader) truncate() error {
+	if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.off, r.head = 0, r.head[:0]
+	return errTruncated
+}
+
+// errTruncated is returned by a followReader when its file has been
+// truncated, for the Scanner to drop the data it holds from before.
+var errTruncated = errors.New("scanner: file truncated")
+
+// truncated drops the data read but not consumed when the file of a
+// Follower has been truncated. It reports whether the empty final chunk
+// of a token being delivered in chunks is to be delivered.
+func (s *Scanner) truncated() bool {
+	s.advance(s.end - s.start)
+	if !s.chunking {
+		return false
+	}
+	if s.discard {
+		s.chunking = false
+		s.discard = false
+		return false
+	}
+	s.endChunks()
+	return true
+}
+
+// wait sleeps for the poll interval, or until the read deadline passes.
+func (r *followReader) wait() error {
+	t := time.NewTimer(r.poll)
+	defer t.Stop()
+	for {
+		r.mu.Lock()
+		deadline := r.deadline
+		r.mu.Unlock()
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return os.ErrDeadlineExceeded
+		}
+
+		select {
+		case <-t.C:
+			return nil
+		case <-r.kick:
+		}
+	}
+}
+
+// SetReadDeadline sets the time after which a Read waiting for data fails
+// with os.ErrDeadlineExceeded. The deadline is checked at least once per
+// poll interval, and right away when it is changed.
+func (r *followReader) SetReadDeadline(t time.Time) error {
+	r.mu.Lock()
+	r.deadline = t
+	r.mu.Unlock()
+	select {
+	case r.kick <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (r *followReader) close() error {
+	if r.f == nil {
+		return os.ErrClosed
+	}
+	if r.next != nil {
+		r.next.Close()
+		r.next = nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
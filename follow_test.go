@@ -0,0 +1,113 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// Test that a Follower sees a file grow, get rotated and get truncated.
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	appendFile := func(name, data string) {
+		t.Helper()
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendFile(path, "one\ntw")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f, err := Follow(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Poll(time.Millisecond)
+
+	lines := make(chan string)
+	done := make(chan error)
+	go func() {
+		for f.Next() {
+			lines <- f.Text()
+		}
+		done <- f.Err()
+	}()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("got %q; want %q", got, want)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	expect("one")
+	select {
+	case got := <-lines:
+		t.Fatalf("got partial line %q", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	appendFile(path, "o\n")
+	expect("two")
+
+	// Rotate, with a line written to the old file just before.
+	appendFile(path, "three\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(path, "four\n")
+	expect("three")
+	expect("four")
+
+	// Truncate.
+	if err := os.WriteFile(path, []byte("5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect("5")
+
+	// Truncate, with a partial line read before.
+	appendFile(path, "partial-garbage-from-before")
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect("new")
+
+	// Truncate and grow past the offset read between polls, as
+	// copytruncate may.
+	if err := os.WriteFile(path, []byte("rewritten and longer than before\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect("rewritten and longer than before")
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Err = %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Next did not return after cancel")
+	}
+}
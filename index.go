@@ -0,0 +1,274 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IndexSuffix is appended to the name of a file to name its index.
+const IndexSuffix = ".idx"
+
+// indexMagic starts the encoding of an Index, followed by its version.
+const (
+	indexMagic   = "LIDX"
+	indexVersion = 1
+)
+
+// ErrBadIndex is returned when decoding an invalid Index.
+var ErrBadIndex = errors.New("scanner: invalid index encoding")
+
+// An Index records where each line of a file starts, so that any line can
+// be read without scanning the lines before it. Lines are as returned by
+// SplitLines.
+type Index struct {
+	Size    int64     // Size of the file indexed.
+	ModTime time.Time // Modification time of the file indexed, if known.
+	starts  []int64   // Offset of the start of each line.
+}
+
+// BuildIndex scans the lines of r and returns their index. Lines longer
+// than the maximum token size are indexed all the same. The ModTime of
+// the index is left for the caller to set.
+func BuildIndex(r io.Reader) (*Index, error) {
+	s := New(r)
+	s.Oversize(OversizeChunk)
+	ix := &Index{}
+	more := false // The next token continues a line.
+	for s.Next() {
+		if !more {
+			ix.starts = append(ix.starts, s.tokPos.Offset)
+		}
+		more = s.Partial()
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	ix.Size = s.pos.Offset
+	return ix, nil
+}
+
+// Lines returns the number of lines in the index.
+func (ix *Index) Lines() int { return len(ix.starts) }
+
+// Span returns the offsets of the start of line n, counting from 1, and
+// of the start of the line after it, or the end of the file.
+func (ix *Index) Span(n int) (start, end int64, ok bool) {
+	if n < 1 || n > len(ix.starts) {
+		return 0, 0, false
+	}
+	end = ix.Size
+	if n < len(ix.starts) {
+		end = ix.starts[n]
+	}
+	return ix.starts[n-1], end, true
+}
+
+// Valid reports whether the index is up to date with the file described
+// by fi, judging by its size and modification time.
+func (ix *Index) Valid(fi os.FileInfo) bool {
+	return ix.Size == fi.Size() && ix.ModTime.Equal(fi.ModTime())
+}
+
+// WriteTo writes the index to w in a compact form: the offsets of the
+// lines are stored as varint-encoded differences.
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	b := append([]byte(indexMagic), indexVersion)
+	b = binary.AppendUvarint(b, uint64(ix.Size))
+	var mtime int64
+	if !ix.ModTime.IsZero() {
+		mtime = ix.ModTime.UnixNano()
+	}
+	b = binary.AppendVarint(b, mtime)
+	b = binary.AppendUvarint(b, uint64(len(ix.starts)))
+	var prev int64
+	for _, start := range ix.starts {
+		b = binary.AppendUvarint(b, uint64(start-prev))
+		prev = start
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// ReadIndex reads an index written by WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(indexMagic)+1 || string(data[:len(indexMagic)]) != indexMagic ||
+		data[len(indexMagic)] != indexVersion {
+		return nil, ErrBadIndex
+	}
+	data = data[len(indexMagic)+1:]
+
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			err = ErrBadIndex
+			return 0
+		}
+		data = data[n:]
+		return v
+	}
+
+	ix := &Index{Size: int64(uvarint())}
+	if err != nil {
+		return nil, err
+	}
+	mtime, n := binary.Varint(data)
+	if n <= 0 {
+		return nil, ErrBadIndex
+	}
+	data = data[n:]
+	if mtime != 0 {
+		ix.ModTime = time.Unix(0, mtime)
+	}
+
+	count := uvarint()
+	if err != nil || count > uint64(len(data)) {
+		return nil, ErrBadIndex
+	}
+	ix.starts = make([]int64, count)
+	var prev int64
+	for i := range ix.starts {
+		prev += int64(uvarint())
+		if err != nil || prev < 0 || prev > ix.Size || i > 0 && prev <= ix.starts[i-1] {
+			return nil, ErrBadIndex
+		}
+		ix.starts[i] = prev
+	}
+	if len(data) > 0 {
+		return nil, ErrBadIndex
+	}
+	return ix, nil
+}
+
+// An IndexedFile is a file whose lines can be read by number, using an
+// Index kept next to the file.
+type IndexedFile struct {
+	f  *os.File
+	ix *Index
+}
+
+// OpenIndexed opens the file named by path for reading lines by number.
+// Its index is read from the file named by path plus IndexSuffix, unless
+// it is missing or no longer valid for the file, in which case the file
+// is scanned and the index written anew. Writing the index is best
+// effort: if it fails, as in a read-only directory, the index is only
+// kept in memory and the file is scanned again the next time it is opened.
+func OpenIndexed(path string) (*IndexedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := loadIndex(f, path+IndexSuffix)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &IndexedFile{f, ix}, nil
+}
+
+// loadIndex returns the index of f read from the file named idx, or
+// rebuilt and, if possible, written there if the file holds no valid index.
+func loadIndex(f *os.File, idx string) (*Index, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if r, err := os.Open(idx); err == nil {
+		ix, err := ReadIndex(r)
+		r.Close()
+		if err == nil && ix.Valid(fi) {
+			return ix, nil
+		}
+	}
+
+	ix, err := BuildIndex(io.NewSectionReader(f, 0, fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	ix.ModTime = fi.ModTime()
+	writeIndex(ix, idx) // The index is good without its file.
+	return ix, nil
+}
+
+// writeIndex writes ix to the file named idx. It writes to a temporary
+// file first, so that the index is replaced at once and never read half
+// written.
+func writeIndex(ix *Index, idx string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(idx), filepath.Base(idx)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = ix.WriteTo(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), idx)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Index returns the index of the file.
+func (f *IndexedFile) Index() *Index { return f.ix }
+
+// Lines returns the number of lines in the file.
+func (f *IndexedFile) Lines() int { return f.ix.Lines() }
+
+// Line returns line n of the file, counting from 1, stripped of its
+// end-of-line marker.
+func (f *IndexedFile) Line(n int) ([]byte, error) {
+	lines, err := f.LineRange(n, n+1)
+	if err != nil {
+		return nil, err
+	}
+	return lines[0], nil
+}
+
+// LineRange returns lines from to to-1 of the file, counting from 1,
+// stripped of their end-of-line markers. They are read with one call to
+// ReadAt.
+func (f *IndexedFile) LineRange(from, to int) ([][]byte, error) {
+	start, _, ok1 := f.ix.Span(from)
+	_, end, ok2 := f.ix.Span(to - 1)
+	if !ok1 || !ok2 || from >= to {
+		return nil, fmt.Errorf("scanner: lines %d to %d out of range [1, %d]", from, to-1, f.ix.Lines())
+	}
+
+	buf := make([]byte, end-start)
+	if _, err := f.f.ReadAt(buf, start); err != nil {
+		return nil, err
+	}
+	lines := make([][]byte, 0, to-from)
+	for n := from; n < to; n++ {
+		s, e, _ := f.ix.Span(n)
+		line := buf[s-start : e-start : e-start]
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			line = line[:len(line)-1]
+		}
+		lines = append(lines, dropCR(line))
+	}
+	return lines, nil
+}
+
+// ReadAt implements the io.ReaderAt interface, reading the file itself.
+func (f *IndexedFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.f.ReadAt(p, off)
+}
+
+// Close closes the file.
+func (f *IndexedFile) Close() error { return f.f.Close() }
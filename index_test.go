@@ -0,0 +1,150 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// checkLines checks that the lines of f are those of text.
+func checkLines(t *testing.T, f *IndexedFile, text string) {
+	t.Helper()
+	var want []string
+	sc := New(strings.NewReader(text))
+	sc.Buffer(nil, len(text)+1)
+	for sc.Next() {
+		want = append(want, sc.Text())
+	}
+	if f.Lines() != len(want) {
+		t.Fatalf("Lines = %d; want %d", f.Lines(), len(want))
+	}
+	for n := 1; n <= len(want); n++ {
+		line, err := f.Line(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != want[n-1] {
+			t.Fatalf("Line(%d) = %q; want %q", n, line, want[n-1])
+		}
+	}
+	if len(want) > 0 {
+		from, to := 1+len(want)/3, 1+len(want)
+		lines, err := f.LineRange(from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, line := range lines {
+			if string(line) != want[from-1+i] {
+				t.Fatalf("LineRange(%d, %d)[%d] = %q; want %q", from, to, i, line, want[from-1+i])
+			}
+		}
+	}
+	if _, err := f.Line(len(want) + 1); err == nil {
+		t.Errorf("Line(%d) succeeded", len(want)+1)
+	}
+}
+
+func TestIndexedFile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	path := filepath.Join(t.TempDir(), "text")
+	for i := 0; i < 20; i++ {
+		text := randomText(rng, rng.Intn(2000))
+		if i == 0 {
+			// A line longer than the maximum token size.
+			text += strings.Repeat("x", 2*MaxScanTokenSize) + "\r\nlast\r"
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Make sure the index goes stale even if the size is unchanged.
+		mtime := time.Now().Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := OpenIndexed(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkLines(t, f, text)
+		built := f.Index()
+		f.Close()
+
+		// The index is read back from its file.
+		f, err = OpenIndexed(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(f.Index(), built) {
+			t.Fatalf("read index %+v; want %+v", f.Index(), built)
+		}
+		checkLines(t, f, text)
+		f.Close()
+	}
+}
+
+// Test that a file in a directory where its index cannot be written is
+// still opened, with its index kept in memory.
+func TestIndexedFileReadOnlyDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to any directory")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "text")
+	text := "one\ntwo\r\nthree"
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0o755)
+
+	f, err := OpenIndexed(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	checkLines(t, f, text)
+	if _, err := os.Stat(path + IndexSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("index written to a read-only directory: %v", err)
+	}
+}
+
+func TestIndexEncoding(t *testing.T) {
+	ix, err := BuildIndex(strings.NewReader("a\n\nbc\r\nd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.ModTime = time.Unix(1, 2)
+	var b bytes.Buffer
+	if _, err := ix.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+
+	got, err := ReadIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, ix) {
+		t.Errorf("ReadIndex = %+v; want %+v", got, ix)
+	}
+	for _, bad := range [][]byte{nil, data[:4], data[:len(data)-1], append(data, 0)} {
+		if _, err := ReadIndex(bytes.NewReader(bad)); !errors.Is(err, ErrBadIndex) {
+			t.Errorf("ReadIndex(%q) = %v; want %v", bad, err, ErrBadIndex)
+		}
+	}
+}
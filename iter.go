@@ -0,0 +1,62 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import "iter"
+
+// All returns an iterator over the tokens of s, as returned by Bytes.
+// As with Bytes, a token may be overwritten by the next iteration.
+// When the iterator stops, Err reports any error that stopped the scan.
+// Breaking out of the loop leaves s just after the last token yielded,
+// so scanning can be continued with Next or another iterator.
+func (s *Scanner) All() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		for s.Next() {
+			if !yield(s.Bytes()) {
+				return
+			}
+		}
+	}
+}
+
+// Strings returns an iterator over the tokens of s, as returned by Text.
+// It stops in the same way as All.
+func (s *Scanner) Strings() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for s.Next() {
+			if !yield(s.Text()) {
+				return
+			}
+		}
+	}
+}
+
+// Positions returns an iterator over the tokens of s and the position
+// of each, as returned by Pos. It stops in the same way as All.
+func (s *Scanner) Positions() iter.Seq2[Position, []byte] {
+	return func(yield func(Position, []byte) bool) {
+		for s.Next() {
+			if !yield(s.Pos(), s.Bytes()) {
+				return
+			}
+		}
+	}
+}
+
+// Results returns an iterator over the tokens of s paired with a nil
+// error. If the scan stops with an error, the iterator yields a final
+// nil token with the error reported by Err.
+func (s *Scanner) Results() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for s.Next() {
+			if !yield(s.Bytes(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
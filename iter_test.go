@@ -0,0 +1,80 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// Test that breaking out of an iterator leaves the Scanner ready to continue.
+func TestIterBreak(t *testing.T) {
+	t.Parallel()
+
+	sc := New(strings.NewReader("a b c d e"))
+	sc.Split(SplitWords)
+
+	var got []string
+	for word := range sc.Strings() {
+		got = append(got, word)
+		if word == "b" {
+			break
+		}
+	}
+	for pos, word := range sc.Positions() {
+		if pos.Column != 2*len(got)+1 {
+			t.Errorf("%q: column %d; want %d", word, pos.Column, 2*len(got)+1)
+		}
+		got = append(got, string(word))
+		break
+	}
+	for word := range sc.All() {
+		got = append(got, string(word))
+	}
+
+	if want := "a b c d e"; want != strings.Join(got, " ") {
+		t.Errorf("got %q; want %q", strings.Join(got, " "), want)
+	}
+	if err := sc.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test that Results yields the error that stopped the scan.
+func TestIterResults(t *testing.T) {
+	t.Parallel()
+
+	sc := New(strings.NewReader("1,2,x,3"))
+	sc.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		advance, token, err = commaSplit(data, atEOF)
+		if string(token) == "x" {
+			return 0, nil, errTest
+		}
+		return
+	})
+
+	var got []string
+	var gotErr error
+	for tok, err := range sc.Results() {
+		if err != nil {
+			if tok != nil {
+				t.Errorf("token %q with error", tok)
+			}
+			gotErr = err
+			continue
+		}
+		got = append(got, string(tok))
+	}
+
+	if want := "1 2"; want != strings.Join(got, " ") {
+		t.Errorf("got %q; want %q", strings.Join(got, " "), want)
+	}
+	if !errors.Is(gotErr, errTest) {
+		t.Errorf("got error %v; want %v", gotErr, errTest)
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of f read-only into memory.
+func mmap(f *os.File, size int) ([]byte, error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is scanned from start to end; let the kernel read ahead.
+	syscall.Madvise(data, syscall.MADV_SEQUENTIAL)
+	return data, nil
+}
+
+// munmap releases a mapping returned by mmap.
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,23 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package scanner
+
+import (
+	"errors"
+	"os"
+)
+
+// mmap maps the first size bytes of f read-only into memory.
+// It is not supported on this system.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// munmap releases a mapping returned by mmap.
+func munmap(data []byte) error {
+	return errors.ErrUnsupported
+}
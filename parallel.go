@@ -0,0 +1,197 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"io"
+	"iter"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// batchSize is the number of bytes of tokens a worker of a Parallel
+// collects before handing them over.
+const batchSize = 64 * 1024
+
+// A Parallel scans an io.ReaderAt using several goroutines. It cuts the
+// input into shards whose boundaries fall between tokens, scans the shards
+// on a pool of workers, and delivers their tokens through All.
+//
+// The shards are planned by Shards, so the split function must meet its
+// requirements. It must also be safe to call from several goroutines at
+// once.
+type Parallel struct {
+	r       io.ReaderAt
+	size    int64
+	split   SplitFunc
+	workers int
+	shards  int // Number of shards; modified by tests.
+	ordered bool
+	err     error
+}
+
+// NewParallel returns a new Parallel to read size bytes from r.
+// The split function defaults to SplitLines, the number of workers to
+// runtime.GOMAXPROCS(0), and tokens are delivered in order.
+func NewParallel(r io.ReaderAt, size int64) *Parallel {
+	return &Parallel{
+		r:       r,
+		size:    size,
+		split:   SplitLines,
+		workers: runtime.GOMAXPROCS(0),
+		ordered: true,
+	}
+}
+
+// Split sets the split function for the Parallel.
+func (p *Parallel) Split(split SplitFunc) {
+	p.split = split
+}
+
+// Workers sets the number of goroutines scanning shards.
+func (p *Parallel) Workers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.workers = n
+}
+
+// Ordered sets whether tokens are delivered in the order of the input.
+// Unordered delivery is faster, as no worker has to wait for the tokens
+// of earlier shards to be consumed before handing over its own.
+func (p *Parallel) Ordered(ordered bool) {
+	p.ordered = ordered
+}
+
+// Err returns the first error that stopped the last scan by All.
+func (p *Parallel) Err() error {
+	return p.err
+}
+
+// A batch is a group of tokens scanned by a worker of a Parallel.
+type batch struct {
+	data []byte // The tokens, one after the other.
+	ends []int  // The end of each token in data.
+	err  error  // The error that stopped the scan of the shard, if any.
+}
+
+// All returns an iterator over the tokens of the input. Each token is
+// only valid until the next iteration. When the iterator stops, Err
+// reports any error that stopped the scan. Breaking out of the loop stops
+// the workers; a later call to All starts the scan again from the
+// beginning.
+func (p *Parallel) All() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		p.err = nil
+
+		n := p.shards
+		if n == 0 {
+			n = int(min(int64(4*p.workers), p.size/batchSize+1))
+		}
+
+		shards, err := Shards(p.r, p.size, n, p.split)
+		if err != nil {
+			p.err = err
+			return
+		}
+		n = len(shards)
+
+		chans := make([]chan batch, 1)
+		if p.ordered {
+			chans = make([]chan batch, n)
+		}
+		for i := range chans {
+			chans[i] = make(chan batch, 2)
+		}
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		defer func() {
+			close(done)
+			wg.Wait()
+		}()
+
+		var next atomic.Int64
+		for w := 0; w < p.workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					i := int(next.Add(1) - 1)
+					if i >= n {
+						return
+					}
+					ch := chans[0]
+					if p.ordered {
+						ch = chans[i]
+					}
+					ok := p.scanShard(shards[i], ch, done)
+					if p.ordered {
+						close(ch)
+					}
+					if !ok {
+						return
+					}
+				}
+			}()
+		}
+		if !p.ordered {
+			go func() {
+				wg.Wait()
+				close(chans[0])
+			}()
+		}
+
+		for _, ch := range chans {
+			for b := range ch {
+				start := 0
+				for _, end := range b.ends {
+					if !yield(b.data[start:end:end]) {
+						return
+					}
+					start = end
+				}
+				if b.err != nil {
+					p.err = b.err
+					return
+				}
+			}
+		}
+	}
+}
+
+// scanShard scans the shard rg, sending its tokens to ch. It reports
+// false if it gave up because done was closed.
+func (p *Parallel) scanShard(rg Range, ch chan<- batch, done <-chan struct{}) bool {
+	send := func(b batch) bool {
+		select {
+		case ch <- b:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	sc := NewRange(p.r, rg)
+	sc.Split(p.split)
+
+	var b batch
+	for sc.Next() {
+		b.data = append(b.data, sc.Bytes()...)
+		b.ends = append(b.ends, len(b.data))
+		if len(b.data) >= batchSize {
+			if !send(b) {
+				return false
+			}
+			b = batch{}
+		}
+	}
+	b.err = sc.Err()
+	if len(b.ends) > 0 || b.err != nil {
+		return send(b)
+	}
+	return true
+}
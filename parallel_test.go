@@ -0,0 +1,110 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// randomText returns text of about n bytes made of random words, spaces
+// and line endings.
+func randomText(rng *rand.Rand, n int) string {
+	pieces := []string{"\n", "\r\n", " ", "\t", "  ", "　", "a", "bc", "¼", "日本語", "\n\n"}
+	var b strings.Builder
+	for b.Len() < n {
+		b.WriteString(pieces[rng.Intn(len(pieces))])
+	}
+	return b.String()
+}
+
+// scanAll returns the tokens of text scanned sequentially with split.
+func scanAll(text string, split SplitFunc) []string {
+	var tokens []string
+	sc := New(strings.NewReader(text))
+	sc.Split(split)
+	for sc.Next() {
+		tokens = append(tokens, sc.Text())
+	}
+	return tokens
+}
+
+// Test that a Parallel finds the same tokens as a sequential Scanner.
+func TestParallel(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		text := randomText(rng, rng.Intn(2000))
+		for _, split := range []SplitFunc{SplitLines, SplitWords} {
+			want := scanAll(text, split)
+
+			for _, ordered := range []bool{true, false} {
+				p := NewParallel(strings.NewReader(text), int64(len(text)))
+				p.Split(split)
+				p.Workers(1 + i%4)
+				p.Shards(1 + rng.Intn(50))
+				p.Ordered(ordered)
+
+				var got []string
+				for tok := range p.All() {
+					got = append(got, string(tok))
+				}
+				if err := p.Err(); err != nil {
+					t.Fatal(err)
+				}
+
+				want := want
+				if !ordered {
+					want = slices.Clone(want)
+					slices.Sort(want)
+					slices.Sort(got)
+				}
+				if !slices.Equal(want, got) {
+					t.Fatalf("%d: ordered=%t: got %d tokens %.100q; want %d tokens %.100q",
+						i, ordered, len(got), got, len(want), want)
+				}
+			}
+		}
+	}
+}
+
+// Test that a Parallel can be stopped early and reports errors.
+func TestParallelStopAndError(t *testing.T) {
+	t.Parallel()
+
+	text := strings.Repeat("line\n", 100000)
+	p := NewParallel(strings.NewReader(text), int64(len(text)))
+	p.Workers(4)
+	count := 0
+	for range p.All() {
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("got %d tokens; want 10", count)
+	}
+
+	text = "short\n" + strings.Repeat("x", 2*MaxScanTokenSize) + "\nshort\n"
+	p = NewParallel(strings.NewReader(text), int64(len(text)))
+	p.Shards(1)
+	count = 0
+	for range p.All() {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("got %d tokens; want 1", count)
+	}
+	if err := p.Err(); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Err = %v; want %v", err, ErrTooLong)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReadAhead makes the Scanner read from its reader in a background
+// goroutine, so that reading the next data overlaps with splitting and
+// processing the tokens of the current data. This helps with slow readers,
+// such as network connections or decompressors, and costs an extra copy
+// of the data otherwise.
+//
+// The goroutine reads into two buffers of size bytes, or MaxScanTokenSize
+// if size <= 0: while the Scanner takes the data of one, the goroutine
+// fills the other. It stops at the first error or EOF, or at Close or
+// Reset, although a Read it is blocked in runs to completion. The tokens
+// are the same as without ReadAhead, but once it is set, Seek no longer
+// works.
+//
+// ReadAhead panics if it is called after scanning has started, or on a
+// Scanner created by NewBytes or NewString, which has nothing to read.
+func (s *Scanner) ReadAhead(size int) {
+	if s.nextCalled {
+		panic("ReadAhead called after Next")
+	}
+	if s.inMemory {
+		panic("ReadAhead called on in-memory Scanner")
+	}
+	if size <= 0 {
+		size = MaxScanTokenSize
+	}
+	if s.prefetch != nil {
+		s.prefetch.stop()
+		s.r = s.prefetch.r
+	}
+	s.prefetch = newPrefetcher(s.r, size)
+	s.r = s.prefetch
+}
+
+// A prefetcher reads ahead from a reader in a background goroutine.
+// Like followReader, it has a SetReadDeadline method so that a Scanner
+// can interrupt a Read waiting for data when its context is done.
+type prefetcher struct {
+	r    io.Reader
+	size int
+
+	full chan block    // Blocks read by the goroutine.
+	free chan []byte   // Buffers for the goroutine to fill.
+	done chan struct{} // Closed to stop the goroutine.
+
+	started bool   // The goroutine has been started.
+	stopped bool   // done has been closed.
+	cur     block  // Block being consumed, if any.
+	buf     []byte // Buffer of cur, to free once consumed.
+
+	mu       sync.Mutex
+	deadline time.Time     // Deadline set by SetReadDeadline.
+	kick     chan struct{} // Signals a change of deadline.
+}
+
+// A block is the result of a Read by a prefetcher.
+type block struct {
+	data []byte
+	err  error
+}
+
+func newPrefetcher(r io.Reader, size int) *prefetcher {
+	return &prefetcher{
+		r:    r,
+		size: size,
+		full: make(chan block, 2),
+		free: make(chan []byte, 2),
+		done: make(chan struct{}),
+		kick: make(chan struct{}, 1),
+	}
+}
+
+// run reads blocks until an error or until stopped.
+func (p *prefetcher) run() {
+	p.free <- make([]byte, p.size)
+	p.free <- make([]byte, p.size)
+	for {
+		var buf []byte
+		select {
+		case buf = <-p.free:
+		case <-p.done:
+			return
+		}
+
+		n, err := p.r.Read(buf)
+		if n < 0 || n > len(buf) {
+			n, err = 0, ErrBadReadCount
+		}
+		select {
+		case p.full <- block{buf[:n], err}:
+		case <-p.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *prefetcher) Read(b []byte) (int, error) {
+	if p.stopped {
+		return 0, os.ErrClosed
+	}
+	if !p.started {
+		p.started = true
+		go p.run()
+	}
+
+	if p.buf == nil {
+		if err := p.wait(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, p.cur.data)
+	p.cur.data = p.cur.data[n:]
+	if len(p.cur.data) > 0 {
+		return n, nil
+	}
+	if p.cur.err != nil {
+		// The goroutine has stopped; keep returning the error.
+		return n, p.cur.err
+	}
+	p.free <- p.buf[:cap(p.buf)]
+	p.buf = nil
+	return n, nil
+}
+
+// wait waits for the next block, or until the read deadline passes.
+func (p *prefetcher) wait() error {
+	for {
+		p.mu.Lock()
+		deadline := p.deadline
+		p.mu.Unlock()
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return os.ErrDeadlineExceeded
+		}
+
+		var timeout <-chan time.Time
+		var t *time.Timer
+		if !deadline.IsZero() {
+			t = time.NewTimer(time.Until(deadline))
+			timeout = t.C
+		}
+
+		select {
+		case p.cur = <-p.full:
+			p.buf = p.cur.data
+		case <-p.kick:
+		case <-timeout:
+		}
+		if t != nil {
+			t.Stop()
+		}
+		if p.buf != nil {
+			return nil
+		}
+	}
+}
+
+// SetReadDeadline sets the time after which a Read waiting for data fails
+// with os.ErrDeadlineExceeded.
+func (p *prefetcher) SetReadDeadline(t time.Time) error {
+	p.mu.Lock()
+	p.deadline = t
+	p.mu.Unlock()
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// stop stops the goroutine.
+func (p *prefetcher) stop() {
+	if !p.stopped {
+		p.stopped = true
+		close(p.done)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// Test that a Scanner set to ReadAhead returns the same tokens and errors
+// as one that is not.
+func TestReadAhead(t *testing.T) {
+	t.Parallel()
+
+	readers := []func(io.Reader) io.Reader{
+		func(r io.Reader) io.Reader { return r },
+		iotest.OneByteReader,
+		iotest.HalfReader,
+		iotest.DataErrReader,
+		func(r io.Reader) io.Reader {
+			return io.MultiReader(r, iotest.ErrReader(errors.New("test error")))
+		},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		text := randomText(rng, rng.Intn(5000))
+		size := 1 + rng.Intn(100)
+		for j, newReader := range readers {
+			for _, split := range []SplitFunc{SplitLines, SplitWords} {
+				scan := func(readAhead bool) ([]string, string) {
+					sc := New(newReader(strings.NewReader(text)))
+					sc.Split(split)
+					if readAhead {
+						sc.ReadAhead(size)
+					}
+					var tokens []string
+					for sc.Next() {
+						tokens = append(tokens, sc.Text())
+					}
+					return tokens, fmt.Sprint(sc.Err())
+				}
+				want, wantErr := scan(false)
+				got, gotErr := scan(true)
+				if !slices.Equal(got, want) || gotErr != wantErr {
+					t.Fatalf("%d: reader %d: got %q, %s; want %q, %s", i, j, got, gotErr, want, wantErr)
+				}
+			}
+		}
+	}
+}
+
+// Test that a Scanner set to ReadAhead can be interrupted by a context and
+// stopped by Close.
+func TestReadAheadContext(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := NewContext(ctx, pr)
+	sc.ReadAhead(16)
+
+	go pw.Write([]byte("one\ntw"))
+	if !sc.Next() || sc.Text() != "one" {
+		t.Fatalf("got %q, %v; want %q", sc.Text(), sc.Err(), "one")
+	}
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if sc.Next() {
+		t.Fatalf("got %q after cancel", sc.Text())
+	}
+	if err := sc.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Err = %v; want %v", err, context.Canceled)
+	}
+
+	go pw.Write([]byte("o\nthree\n"))
+	if !sc.NextContext(context.Background()) || sc.Text() != "two" {
+		t.Fatalf("got %q, %v; want %q", sc.Text(), sc.Err(), "two")
+	}
+
+	if err := sc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if sc.Next() {
+		t.Errorf("got %q after Close", sc.Text())
+	}
+}
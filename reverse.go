@@ -0,0 +1,230 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"bytes"
+	"io"
+)
+
+// ReverseScanner reads the delimiter-terminated tokens of an input, such
+// as its lines, from last to first. It reads the input backwards in
+// blocks, so the last lines of a large file are found without reading the
+// rest of it.
+//
+// With the default delimiter '\n', the tokens are those SplitLines would
+// return, in reverse order: a final line without a newline is still a
+// token, a newline at the very end of the input does not start an empty
+// one, and a carriage return before the newline is dropped. Tokens are
+// limited to the maximum token size, as with Scanner, and errors are
+// reported as a *ScanError whose Pos holds only the offset.
+type ReverseScanner struct {
+	r            io.ReaderAt // The input provided by the client.
+	off          int64       // Offset in the input of buf[start].
+	delim        byte        // The byte ending each token.
+	maxTokenSize int         // Maximum size of a token.
+	token        []byte      // Last token found.
+	tokPos       Position    // Position of the first byte of token.
+	buf          []byte      // Buffer of input, filled from the end.
+	start        int         // First byte of unprocessed data in buf.
+	end          int         // End of unprocessed data in buf.
+	tokens       int         // Count of tokens returned.
+	nextCalled   bool        // Next has been called; buffer is in use.
+	done         bool        // Scan has finished.
+	err          error       // Sticky error.
+}
+
+// NewReverse returns a new ReverseScanner to read the size bytes of r
+// backwards. The delimiter defaults to '\n'.
+func NewReverse(r io.ReaderAt, size int64) *ReverseScanner {
+	return &ReverseScanner{
+		r:            r,
+		off:          size,
+		delim:        '\n',
+		maxTokenSize: MaxScanTokenSize,
+	}
+}
+
+// NewReverseSeeker returns a new ReverseScanner to read rs backwards,
+// from its end to its current offset. Unless rs is also an io.ReaderAt,
+// the ReverseScanner reads it by seeking, and rs must not be used while
+// the scan is in progress.
+func NewReverseSeeker(rs io.ReadSeeker) (*ReverseScanner, error) {
+	cur, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	size := max(end-cur, 0)
+
+	var r io.ReaderAt = seekReaderAt{rs, cur}
+	if ra, ok := rs.(io.ReaderAt); ok {
+		r = io.NewSectionReader(ra, cur, size)
+	}
+	return NewReverse(r, size), nil
+}
+
+// seekReaderAt reads an io.ReadSeeker at offsets relative to base.
+type seekReaderAt struct {
+	rs   io.ReadSeeker
+	base int64
+}
+
+func (r seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := r.rs.Seek(r.base+off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.rs, p)
+}
+
+// Delim sets the byte that ends each token. A delimiter other than '\n'
+// leaves carriage returns in the tokens. Delim panics if it is called
+// after scanning has started.
+func (s *ReverseScanner) Delim(delim byte) {
+	if s.nextCalled {
+		panic("Delim called after Next")
+	}
+	s.delim = delim
+}
+
+// Buffer sets the initial buffer to use when scanning and the maximum
+// size of buffer that may be allocated during scanning, as for
+// Scanner.Buffer. Buffer panics if it is called after scanning has
+// started.
+func (s *ReverseScanner) Buffer(buf []byte, max int) {
+	if s.nextCalled {
+		panic("Buffer called after Next")
+	}
+	s.buf = buf[:cap(buf)]
+	s.maxTokenSize = max
+}
+
+// Err returns the first error that was encountered by the ReverseScanner.
+func (s *ReverseScanner) Err() error { return s.err }
+
+// Bytes returns the most recent token generated by a call to Next.
+// The underlying array may point to data that will be overwritten
+// by a subsequent call to Next. It does no allocation.
+func (s *ReverseScanner) Bytes() []byte { return s.token }
+
+// Text returns the most recent token generated by a call to Next
+// as a newly allocated string holding its bytes.
+func (s *ReverseScanner) Text() string { return string(s.token) }
+
+// Pos returns the position of the first byte of the most recent token
+// generated by a call to Next. Only its offset is known.
+func (s *ReverseScanner) Pos() Position { return s.tokPos }
+
+// Next advances the ReverseScanner to the token before the current one,
+// which will then be available through the Bytes or Text method. It
+// returns false when the scan stops, either by reaching the beginning of
+// the input or an error.
+func (s *ReverseScanner) Next() bool {
+	if s.done {
+		return false
+	}
+	if !s.nextCalled {
+		s.nextCalled = true
+		if s.off == 0 {
+			s.done = true
+			return false
+		}
+		s.start, s.end = len(s.buf), len(s.buf)
+		// A delimiter ending the input does not start an empty token.
+		if !s.fill() {
+			return false
+		}
+		if s.end > s.start && s.buf[s.end-1] == s.delim {
+			s.end--
+		}
+	}
+
+	for {
+		data := s.buf[s.start:s.end]
+		if i := bytes.LastIndexByte(data, s.delim); i >= 0 {
+			s.setToken(data[i+1:], s.off+int64(i+1))
+			s.end = s.start + i
+			return true
+		}
+		if s.off == 0 {
+			// The rest of the input is the first token.
+			s.setToken(data, 0)
+			s.done = true
+			return true
+		}
+		if !s.fill() {
+			return false
+		}
+	}
+}
+
+// setToken records token, found at offset off in the input.
+func (s *ReverseScanner) setToken(token []byte, off int64) {
+	if s.delim == '\n' {
+		token = dropCR(token)
+	}
+	s.token = token
+	s.tokPos = Position{Offset: off}
+	s.tokens++
+}
+
+// fill reads the block of input before the unprocessed data, growing the
+// buffer if it is full. It reports whether scanning may go on.
+func (s *ReverseScanner) fill() bool {
+	if s.off == 0 {
+		return true
+	}
+
+	if s.end-s.start == len(s.buf) {
+		// The buffer is full, or not allocated yet. Resize.
+		// Guarantee no overflow in the multiplication below.
+		const maxInt = int(^uint(0) >> 1)
+		if len(s.buf) >= s.maxTokenSize || len(s.buf) > maxInt/2 {
+			s.setErr(ErrTooLong)
+			return false
+		}
+		newSize := len(s.buf) * 2
+		if newSize == 0 {
+			newSize = startBufSize
+		}
+		if newSize > s.maxTokenSize {
+			newSize = s.maxTokenSize
+		}
+		s.buf = s.move(make([]byte, newSize))
+	} else if s.end < len(s.buf) {
+		// Move the data to the end of the buffer to make room before it.
+		s.buf = s.move(s.buf)
+	}
+
+	n := int(min(int64(s.start), s.off))
+	m, err := s.r.ReadAt(s.buf[s.start-n:s.start], s.off-int64(n))
+	if m < n {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		s.setErr(err)
+		return false
+	}
+	s.start -= n
+	s.off -= int64(n)
+	return true
+}
+
+// move copies the unprocessed data to the end of buf and returns it.
+func (s *ReverseScanner) move(buf []byte) []byte {
+	n := copy(buf[len(buf)-(s.end-s.start):], s.buf[s.start:s.end])
+	s.start, s.end = len(buf)-n, len(buf)
+	return buf
+}
+
+// setErr records err, wrapped in a *ScanError, and stops the scan.
+func (s *ReverseScanner) setErr(err error) {
+	s.err = &ScanError{Pos: Position{Offset: s.off}, Token: s.tokens, Err: err}
+	s.done = true
+	s.token = nil
+}
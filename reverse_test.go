@@ -0,0 +1,109 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// Test that a ReverseScanner finds the lines of a Scanner, at the same
+// offsets, in reverse order.
+func TestReverse(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	texts := []string{"", "\n", "\n\n", "a", "a\n", "a\r\n", "\na", "a\nb", "a\n\nb\n"}
+	for i := 0; i < 200; i++ {
+		texts = append(texts, randomText(rng, rng.Intn(2000)))
+	}
+	for i, text := range texts {
+		var want []string
+		var wantOffsets []int64
+		sc := New(strings.NewReader(text))
+		for sc.Next() {
+			want = append(want, sc.Text())
+			wantOffsets = append(wantOffsets, sc.Pos().Offset)
+		}
+		slices.Reverse(want)
+		slices.Reverse(wantOffsets)
+
+		rs := NewReverse(strings.NewReader(text), int64(len(text)))
+		rs.Buffer(make([]byte, 0, 1+rng.Intn(10)), MaxScanTokenSize)
+		var got []string
+		var gotOffsets []int64
+		for rs.Next() {
+			got = append(got, rs.Text())
+			gotOffsets = append(gotOffsets, rs.Pos().Offset)
+		}
+		if err := rs.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(want, got) {
+			t.Fatalf("%d: got %q; want %q", i, got, want)
+		}
+		if !slices.Equal(wantOffsets, gotOffsets) {
+			t.Fatalf("%d: got offsets %v; want %v", i, gotOffsets, wantOffsets)
+		}
+	}
+}
+
+// onlySeeker hides all methods but Read and Seek.
+type onlySeeker struct {
+	io.ReadSeeker
+}
+
+// Test a ReverseScanner of an io.ReadSeeker with a delimiter other than
+// '\n'.
+func TestReverseSeeker(t *testing.T) {
+	for _, hide := range []bool{false, true} {
+		var rs io.ReadSeeker = strings.NewReader("skip\x00a\r\x00\x00bc\x00")
+		if hide {
+			rs = onlySeeker{rs}
+		}
+		if _, err := rs.Seek(5, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		sc, err := NewReverseSeeker(rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc.Delim(0)
+		var got []string
+		for sc.Next() {
+			got = append(got, sc.Text())
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"bc", "", "a\r"}; !slices.Equal(got, want) {
+			t.Errorf("hide=%t: got %q; want %q", hide, got, want)
+		}
+	}
+}
+
+// Test that a ReverseScanner stops at a line that is too long.
+func TestReverseTooLong(t *testing.T) {
+	text := "short\n" + strings.Repeat("x", 100) + "\nlast\n"
+	sc := NewReverse(strings.NewReader(text), int64(len(text)))
+	sc.Buffer(nil, 64)
+	var got []string
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if want := []string{"last"}; !slices.Equal(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	var serr *ScanError
+	if err := sc.Err(); !errors.Is(err, ErrTooLong) || !errors.As(err, &serr) || serr.Token != 1 {
+		t.Errorf("Err = %v; want %v after 1 token", err, ErrTooLong)
+	}
+}
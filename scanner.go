@@ -6,12 +6,15 @@ package scanner
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"unicode/utf8"
+	"unsafe"
 )
 
-// Errors returned by Scanner.
+// Errors returned by Scanner, wrapped in a *ScanError.
 var (
 	ErrTooLong         = errors.New("scanner.Scanner: token too long")
 	ErrNegativeAdvance = errors.New("scanner.Scanner: SplitFunc returns negative advance count")
@@ -39,28 +42,132 @@ const (
 // lines, bytes, UTF-8-encoded runes, and space-delimited words. The
 // client may instead provide a custom split function.
 //
-// Scanning stops unrecoverably at EOF, the first I/O error, or a token too
-// large to fit in the buffer. When a scan stops, the reader may have
-// advanced arbitrarily far past the last token. Programs that need more
-// control over error handling or large tokens, or must run sequential scans
-// on a reader, should use bufio.Reader instead.
+// Scanning stops at EOF, the first I/O error, an error returned by the
+// split function, or a token too large to fit in the buffer. Programs that
+// need more control can recover from split errors with Recover, deliver
+// large tokens in chunks, or truncate or skip them, with Oversize, and
+// give up on a blocked read without stopping the scan with NextContext.
+// The reader may have advanced past the last token, but Reader returns the
+// rest of the input, buffered or not, so that another scan or decoder can
+// take over from there.
 type Scanner struct {
-	split        SplitFunc // The function to split the tokens.
-	maxTokenSize int       // Maximum size of a token; modified by tests.
-	token        []byte    // Last token returned by split.
-	buf          []byte    // Buffer used as argument to split.
-	start        int       // First non-processed byte in buf.
-	end          int       // End of data in buf.
+	split        SplitFunc  // The function to split the tokens.
+	splitState   SplitState // State of split saved by Checkpoint, if any.
+	resumed      []byte     // State from Resume for SplitState to restore.
+	maxTokenSize int        // Maximum size of a token; modified by tests.
+	token        []byte     // Last token returned by split.
+	buf          []byte     // Buffer used as argument to split.
+	start        int        // First non-processed byte in buf.
+	end          int        // End of data in buf.
 
 	empties int // Count of successive empty tokens.
+	tokens  int // Count of tokens returned.
+
+	oversize  OversizePolicy // What to do with tokens too large for buf.
+	chunking  bool           // A token is being delivered in chunks.
+	discard   bool           // The chunks of the token are being dropped.
+	partial   bool           // Token is a chunk with more to follow.
+	oversized int            // Count of tokens larger than maxTokenSize.
+
+	recover    RecoverFunc  // The function to recover from split errors.
+	recovering *ScanError   // The split error being recovered from.
+	recovered  []*ScanError // Split errors recovered from.
+
+	pos      Position // Position of buf[start] in the input.
+	tokPos   Position // Position of the first byte of token.
+	tokEnd   Position // Position just past the last byte of token.
+	consumed Position // Position just past the input consumed for token.
+	prior    Position // Value of consumed before token.
+	index    int      // Count of tokens up to and including token.
 
 	nextCalled bool // Next has been called; buffer is in use.
 	done       bool // Scan has finished.
 
 	r   io.Reader // The reader provided by the client.
 	err error     // Sticky error.
+
+	inMemory bool   // buf is the whole input, provided by the client.
+	str      string // The input of NewString, sharing memory with buf.
+	mapping  []byte // The memory mapping of NewFile, if any.
+
+	ctx       context.Context // Context given to NewContext, if any.
+	pending   chan readResult // Read abandoned when a context was done.
+	spare     []byte          // Buffer for reads in the background.
+	cancelErr error           // Error from a done context; cleared by Next.
+
+	ahead     []lookahead // Tokens read ahead by Peek or pushed back by Unread.
+	canUnread bool        // Token may be pushed back by Unread.
+
+	prefetch *prefetcher // Reader reading ahead in the background, if any.
+}
+
+// A lookahead is a token held for a later call to Next.
+type lookahead struct {
+	token           []byte
+	tokPos, tokEnd  Position
+	consumed, prior Position
+	index           int
+	partial         bool
+}
+
+// A ScanError records an error that stopped a Scanner and where in the
+// input it occurred. Err returns errors of this type, so the underlying
+// cause, for instance ErrTooLong or an error returned by the split
+// function, should be tested for with errors.Is.
+type ScanError struct {
+	Pos   Position // Position of the first unconsumed byte.
+	Token int      // Index of the token being scanned, starting at 0.
+	Err   error    // The underlying error.
+}
+
+func (e *ScanError) Error() string {
+	if !e.Pos.IsValid() {
+		return fmt.Sprintf("offset %d: %v", e.Pos.Offset, e.Err)
+	}
+	return fmt.Sprintf("line %d: %v", e.Pos.Line, e.Err)
+}
+
+func (e *ScanError) Unwrap() error { return e.Err }
+
+// Position is a location in the input of a Scanner.
+// A Position with a zero Line, as kept by a Scanner that started in the
+// middle of the input, records only the offset.
+type Position struct {
+	Offset int64 // byte offset, starting at 0
+	Line   int   // line number, starting at 1
+	Column int   // column number, starting at 1 (byte count)
+}
+
+// startPos is the position of the first byte of the input.
+var startPos = Position{Line: 1, Column: 1}
+
+// IsValid reports whether the position has a line number.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return fmt.Sprintf("offset %d", p.Offset)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// advancePos returns p moved past the bytes in b.
+func advancePos(p Position, b []byte) Position {
+	p.Offset += int64(len(b))
+	if !p.IsValid() {
+		return p
+	}
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		p.Line += bytes.Count(b, newline)
+		p.Column = len(b) - i
+	} else {
+		p.Column += len(b)
+	}
+	return p
 }
 
+var newline = []byte{'\n'}
+
 // SplitFunc is the signature of the split function used to tokenize the
 // input. The arguments are an initial substring of the remaining unprocessed
 // data and a flag, atEOF, that reports whether the Reader has no more data
@@ -83,6 +190,9 @@ type Scanner struct {
 // The function is never called with an empty data slice unless atEOF
 // is true. If atEOF is true, however, data may be non-empty and,
 // as always, holds unprocessed text.
+//
+// The function must not modify data, which may be read-only memory, as
+// with the Scanners returned by NewString and NewFile.
 type SplitFunc func(data []byte, atEOF bool) (advance int, token []byte, err error)
 
 // New returns a new Scanner to read from r.
@@ -91,29 +201,92 @@ func New(r io.Reader) *Scanner {
 	return &Scanner{
 		split:        SplitLines,
 		maxTokenSize: MaxScanTokenSize,
+		pos:          startPos,
+		consumed:     startPos,
 		r:            r,
 	}
 }
 
+// NewBytes returns a new Scanner to read from b. No data is copied: the
+// tokens are the sub-slices of b returned by the split function, and stay
+// valid, rather than only until the next call to Next, as long as b is not
+// modified. Since all of the input is in memory, there is no maximum token
+// size. The split function defaults to SplitLines. The data passed to the
+// split function is b itself, so, as always, it must not be modified.
+func NewBytes(b []byte) *Scanner {
+	return &Scanner{
+		split:        SplitLines,
+		maxTokenSize: MaxScanTokenSize,
+		buf:          b,
+		end:          len(b),
+		pos:          startPos,
+		consumed:     startPos,
+		err:          io.EOF,
+		inMemory:     true,
+	}
+}
+
+// NewString returns a new Scanner to read from str. Like NewBytes, it
+// copies no data, and Text returns substrings of str rather than newly
+// allocated strings. The slices returned by Bytes share memory with str
+// and must not be modified. Neither may the data passed to the split
+// function, which is read-only memory: writing to it crashes the program.
+// To scan str with a split function that modifies its data, use
+// New(strings.NewReader(str)), which copies str into the Scanner's buffer.
+func NewString(str string) *Scanner {
+	s := NewBytes(unsafe.Slice(unsafe.StringData(str), len(str)))
+	s.str = str
+	return s
+}
+
 // Reset discards any buffered data, resets all state, and switches
 // the scanner to read from r.
 // Calling Reset on the zero value of Scanner sets the maximum token
 // size to MaxScanTokenSize.
+// The memory mapping of a Scanner created by NewFile is kept until Close.
+// A Scanner set to ReadAhead stops reading ahead from the old reader and
+// reads ahead from r.
 func (s *Scanner) Reset(r io.Reader) {
 	if s.maxTokenSize == 0 {
 		s.maxTokenSize = MaxScanTokenSize
 	}
 
+	prefetch := s.prefetch
+	buf := s.buf
+	if s.inMemory {
+		// The buffer is the old input, not ours to overwrite.
+		buf = nil
+	}
+
 	*s = Scanner{
 		split:        s.split,
+		splitState:   s.splitState,
 		maxTokenSize: s.maxTokenSize,
-		buf:          s.buf,
+		buf:          buf,
+		oversize:     s.oversize,
+		recover:      s.recover,
+		pos:          startPos,
+		consumed:     startPos,
 		r:            r,
+		ctx:          s.ctx,
+		mapping:      s.mapping,
+	}
+
+	if p := prefetch; p != nil {
+		p.stop()
+		s.prefetch = newPrefetcher(r, p.size)
+		s.r = s.prefetch
 	}
 }
 
 // Err returns the first non-EOF error that was encountered by the Scanner.
+// The error is a *ScanError recording where scanning stopped.
+// If the last call to Next returned false because its context was done,
+// Err returns the context's error instead, also as a *ScanError.
 func (s *Scanner) Err() error {
+	if s.cancelErr != nil {
+		return s.cancelErr
+	}
 	if io.EOF == s.err {
 		return nil
 	}
@@ -126,11 +299,32 @@ func (s *Scanner) Err() error {
 func (s *Scanner) Bytes() []byte { return s.token }
 
 // Text returns the most recent token generated by a call to Next
-// as a newly allocated string holding its bytes.
+// as a newly allocated string holding its bytes, or, for a Scanner
+// created by NewString, as a substring of the input.
 func (s *Scanner) Text() string {
+	if s.str != "" {
+		if i := tokenIndex(s.buf, s.token); i >= 0 {
+			return s.str[i : i+len(s.token)]
+		}
+	}
 	return string(s.token)
 }
 
+// Partial reports whether the most recent token generated by a call to
+// Next is only a chunk of a larger token, whose remaining chunks will be
+// returned by the following calls to Next. See OversizeChunk.
+func (s *Scanner) Partial() bool { return s.partial }
+
+// Pos returns the position of the first byte of the most recent token
+// generated by a call to Next.
+func (s *Scanner) Pos() Position { return s.tokPos }
+
+// Span returns the position of the first byte of the most recent token
+// generated by a call to Next and the position just past its last byte.
+// If the split function returned a token that is not a sub-slice of its
+// data argument, the span covers the input consumed to produce it.
+func (s *Scanner) Span() (start, end Position) { return s.tokPos, s.tokEnd }
+
 // ErrFinalToken is a special sentinel error value. It is intended to be
 // returned by a Split function to indicate that the token being delivered
 // with the error is the last token and scanning should stop after this one.
@@ -153,6 +347,37 @@ const maxConsecutiveEmptyReads = 100
 // tokens without advancing the input. This is a common error mode for
 // scanners.
 func (s *Scanner) Next() bool {
+	return s.next(s.ctx)
+}
+
+func (s *Scanner) next(ctx context.Context) bool {
+	s.cancelErr = nil
+	if len(s.ahead) > 0 {
+		s.restore(s.ahead[0])
+		s.ahead = s.ahead[1:]
+		s.canUnread = true
+		return true
+	}
+
+	s.canUnread = s.scanToken(ctx)
+	return s.canUnread
+}
+
+// scanToken is like scan, but also records how much of the input has
+// been consumed once the new token is.
+func (s *Scanner) scanToken(ctx context.Context) bool {
+	prior := s.consumed
+	if !s.scan(ctx) {
+		return false
+	}
+	s.consumed = s.pos
+	s.prior = prior
+	s.index = s.tokens
+	return true
+}
+
+// scan advances the Scanner to the next token of the input.
+func (s *Scanner) scan(ctx context.Context) bool {
 	if s.done {
 		return false
 	}
@@ -161,27 +386,73 @@ func (s *Scanner) Next() bool {
 
 	// Loop until we have a token.
 	for {
+		// If we are recovering from a split error, skip input as the
+		// recovery function directs before splitting again.
+		if s.recovering != nil && (s.start < s.end || s.err != nil) {
+			if !s.resync() {
+				return false
+			}
+		}
+
 		// See if we can get a token with what we already have.
 		// If we've run out of data but have an error, give the split function
 		// a chance to recover any remaining, possibly empty token.
-		if s.start < s.end || s.err != nil {
-			advance, token, err := s.split(s.buf[s.start:s.end], s.err != nil)
+		if s.recovering == nil && (s.start < s.end || s.err != nil) {
+			data := s.buf[s.start:s.end]
+			advance, token, err := s.split(data, s.err != nil)
+			if _, ok := err.(keepBack); ok {
+				err = ErrFinalToken
+			}
+			discard := false
+			if s.chunking && (advance > 0 || token != nil || err != nil) {
+				ended := (err == nil || ErrFinalToken == err) && tokenIndex(data, token) != 0
+				if ended && !s.discard {
+					// The token being chunked ended with the last chunk.
+					s.endChunks()
+					return true
+				}
+				discard = !ended && s.discard
+				s.chunking = false
+				s.discard = false
+			}
+			s.partial = false
+
 			if ErrFinalToken == err {
+				if discard {
+					s.done = true
+					return false
+				}
 				s.token = token
+				if advance < 0 || advance > len(data) {
+					advance = len(data)
+				}
+				s.setSpan(s.pos, data, token, advance)
+				s.pos = advancePos(s.pos, data[:advance])
 				s.done = true
+				s.tokens++
 				return true
 			}
 			if err != nil {
+				if s.recover != nil {
+					s.recovering = &ScanError{Pos: s.pos, Token: s.tokens, Err: err}
+					continue
+				}
 				s.setErr(err)
 				return false
 			}
 
+			pos := s.pos
 			if !s.advance(advance) {
 				return false
 			}
 
+			if discard {
+				// Drop the rest of a truncated or skipped token.
+				token = nil
+			}
 			s.token = token
 			if token != nil {
+				s.setSpan(pos, data, token, advance)
 				if s.err == nil || advance > 0 {
 					s.empties = 0
 				} else {
@@ -191,6 +462,7 @@ func (s *Scanner) Next() bool {
 						panic("scanner.Next: too many empty tokens without progressing")
 					}
 				}
+				s.tokens++
 				return true
 			}
 		}
@@ -198,6 +470,13 @@ func (s *Scanner) Next() bool {
 		// We cannot generate a token with what we are holding.
 		// If we've already hit EOF or an I/O error, we are done.
 		if s.err != nil {
+			if s.chunking && !s.discard {
+				s.endChunks()
+				return true
+			}
+			s.chunking = false
+			s.discard = false
+
 			// Shut it down.
 			s.start = 0
 			s.end = 0
@@ -217,6 +496,14 @@ func (s *Scanner) Next() bool {
 			// Guarantee no overflow in the multiplication below.
 			const maxInt = int(^uint(0) >> 1)
 			if len(s.buf) >= s.maxTokenSize || len(s.buf) > maxInt/2 {
+				if s.oversize != OversizeFail {
+					if deliver, ok := s.chunk(); ok {
+						if deliver {
+							return true
+						}
+						continue
+					}
+				}
 				s.setErr(ErrTooLong)
 				return false
 			}
@@ -239,13 +526,25 @@ func (s *Scanner) Next() bool {
 		// a misbehaving Reader. Officially we don't need to do this, but let's
 		// be extra careful: Scanner is for safe, simple jobs.
 		for loop := 0; ; {
-			n, err := s.r.Read(s.buf[s.end:len(s.buf)])
+			n, err, cerr := s.read(ctx, s.buf[s.end:len(s.buf)])
 			if n < 0 || n > len(s.buf)-s.end {
 				s.setErr(ErrBadReadCount)
 				break
 			}
 			s.end += n
 
+			if cerr != nil {
+				s.cancelErr = &ScanError{Pos: s.pos, Token: s.tokens, Err: cerr}
+				return false
+			}
+
+			if errTruncated == err {
+				if s.truncated() {
+					return true
+				}
+				break
+			}
+
 			if err != nil {
 				s.setErr(err)
 				break
@@ -265,6 +564,236 @@ func (s *Scanner) Next() bool {
 	}
 }
 
+// resync calls the recovery function to skip input after a split error.
+// It reports whether scanning may go on.
+func (s *Scanner) resync() bool {
+	cause := s.recovering
+	advance, done, err := s.recover(s.buf[s.start:s.end], s.err != nil, cause.Err)
+	if err != nil {
+		s.recovering = nil
+		s.setErr(err)
+		return false
+	}
+	if !s.advance(advance) {
+		return false
+	}
+
+	// At EOF no more input is coming, so the recovery is over.
+	if !done && s.err == nil {
+		return true
+	}
+
+	s.recovering = nil
+	if s.pos.Offset == cause.Pos.Offset {
+		// Nothing was skipped: splitting again would fail again.
+		s.setErr(cause.Err)
+		return false
+	}
+	s.recovered = append(s.recovered, cause)
+	return true
+}
+
+// chunk consumes the start of the token at the beginning of the full
+// buffer, as the oversize policy directs. It reports whether it was able
+// to, and whether the chunk is to be delivered as the current token.
+func (s *Scanner) chunk() (deliver, ok bool) {
+	data := s.buf[s.start:s.end]
+	advance, token, err := s.split(data, true)
+	keep := utf8.UTFMax - 1
+	if k, ok := err.(keepBack); ok {
+		keep = max(keep, int(k))
+		err = ErrFinalToken
+	}
+	if err != nil && ErrFinalToken != err || advance < 0 || advance > len(data) {
+		return false, false
+	}
+
+	i := tokenIndex(data, token)
+	if i < 0 {
+		return false, false
+	}
+
+	// Keep back enough bytes that no delimiter is cut in two.
+	n := min(i+len(token), len(data)-keep)
+	if n <= i {
+		return false, false
+	}
+
+	first := !s.chunking
+	if first {
+		s.oversized++
+	}
+
+	pos := s.pos
+	s.advance(n)
+	s.chunking = true
+	s.empties = 0
+	switch {
+	case s.oversize == OversizeChunk:
+		s.partial = true
+	case s.oversize == OversizeTruncate && first:
+		s.discard = true
+	default:
+		s.discard = true
+		return false, true
+	}
+
+	s.token = data[i:n]
+	s.setSpan(pos, data, s.token, n)
+	s.tokens++
+	return true, true
+}
+
+// endChunks delivers the empty final chunk of a token that ended
+// with the previous chunk.
+func (s *Scanner) endChunks() {
+	s.token = s.buf[s.start:s.start]
+	s.tokPos = s.pos
+	s.tokEnd = s.pos
+	s.chunking = false
+	s.partial = false
+	s.tokens++
+}
+
+// Buffered returns the number of bytes that have been read from the
+// underlying reader but not yet consumed.
+func (s *Scanner) Buffered() int { return s.end - s.start }
+
+// Reader returns a reader for the rest of the input: the bytes that are
+// buffered but not yet consumed, followed by the rest of the underlying
+// reader. This lets the input be handed to another decoder once Scanner
+// is done with it, for instance after a header.
+//
+// Reading from the returned reader consumes the input, so that a later
+// call to Next resumes after the bytes read. Tokens held by Peek or
+// Unread have already been consumed and are not part of the rest of the
+// input. If the scan has stopped with an error, the reader returns it
+// once the buffered bytes have been read.
+func (s *Scanner) Reader() io.Reader { return remainder{s} }
+
+// remainder is the reader returned by Scanner.Reader.
+type remainder struct {
+	s *Scanner
+}
+
+func (r remainder) Read(p []byte) (int, error) {
+	s := r.s
+	if s.start == s.end && s.pending != nil {
+		// Collect the read left running by a cancelled NextContext into
+		// the buffer, since it may hold more than p.
+		res := <-s.pending
+		s.pending = nil
+		s.spare = res.buf
+		if res.n < 0 || res.n > len(res.buf) {
+			return 0, ErrBadReadCount
+		}
+		if len(s.buf) < res.n {
+			s.buf = make([]byte, res.n)
+		}
+		s.start = 0
+		s.end = copy(s.buf, res.buf[:res.n])
+		if res.err != nil {
+			s.setErr(res.err)
+		}
+	}
+	if s.start < s.end {
+		n := copy(p, s.buf[s.start:s.end])
+		s.advance(n)
+		return n, nil
+	}
+	if s.err != nil {
+		if io.EOF == s.err {
+			return 0, io.EOF
+		}
+		return 0, s.err
+	}
+
+	n, err, _ := s.read(nil, p)
+	if n < 0 || n > len(p) {
+		return 0, ErrBadReadCount
+	}
+	s.pos = advancePos(s.pos, p[:n])
+	return n, err
+}
+
+// ErrInvalidUnread is returned by Unread when there is no token to push back.
+var ErrInvalidUnread = errors.New("scanner.Scanner: invalid use of Unread")
+
+// Peek returns the token that the next call to Next will return, without
+// consuming it, or false if there is none. See PeekN.
+func (s *Scanner) Peek() ([]byte, bool) {
+	tokens := s.PeekN(1)
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	return tokens[0], true
+}
+
+// PeekN returns the next n tokens that Next will return, without
+// consuming them. It returns fewer tokens if the scan stops before then,
+// in which case Err reports why, even though Next will still return the
+// tokens peeked at. It returns nil if n <= 0.
+//
+// The tokens read ahead are copied, as is the current token, so unlike
+// the result of Bytes they stay valid as the scan goes on. Tokens of a
+// Scanner created by NewBytes or NewString are valid anyway and not copied. They are held
+// in memory until returned by Next, so n should be small.
+func (s *Scanner) PeekN(n int) [][]byte {
+	if n <= 0 {
+		return nil
+	}
+	if len(s.ahead) < n {
+		cur := s.save()
+		for len(s.ahead) < n && s.scanToken(s.ctx) {
+			s.ahead = append(s.ahead, s.save())
+		}
+		s.restore(cur)
+	}
+
+	tokens := make([][]byte, min(n, len(s.ahead)))
+	for i := range tokens {
+		tokens[i] = s.ahead[i].token
+	}
+	return tokens
+}
+
+// Unread pushes the most recent token back, so that the next call to
+// Next returns it again. Only the token returned by the last call to Next
+// can be pushed back, and only once; otherwise Unread returns
+// ErrInvalidUnread. After Unread, there is no current token.
+func (s *Scanner) Unread() error {
+	if !s.canUnread {
+		return ErrInvalidUnread
+	}
+	s.canUnread = false
+
+	s.ahead = append(s.ahead, lookahead{})
+	copy(s.ahead[1:], s.ahead)
+	s.ahead[0] = s.save()
+	s.restore(lookahead{consumed: s.prior, index: s.index - 1})
+	return nil
+}
+
+// save returns a copy of the current token.
+func (s *Scanner) save() lookahead {
+	token := s.token
+	if token != nil && !s.inMemory {
+		token = append([]byte{}, token...)
+	}
+	return lookahead{token, s.tokPos, s.tokEnd, s.consumed, s.prior, s.index, s.partial}
+}
+
+// restore makes t the current token.
+func (s *Scanner) restore(t lookahead) {
+	s.token = t.token
+	s.tokPos = t.tokPos
+	s.tokEnd = t.tokEnd
+	s.consumed = t.consumed
+	s.prior = t.prior
+	s.index = t.index
+	s.partial = t.partial
+}
+
 // advance consumes n bytes of the buffer. It reports whether the advance was legal.
 func (s *Scanner) advance(n int) bool {
 	if n < 0 {
@@ -277,13 +806,50 @@ func (s *Scanner) advance(n int) bool {
 		return false
 	}
 
+	s.pos = advancePos(s.pos, s.buf[s.start:s.start+n])
 	s.start += n
 	return true
 }
 
-// setErr records the first error encountered.
+// setSpan records the span of token, which was split from data at
+// position pos and consumed n bytes of it.
+func (s *Scanner) setSpan(pos Position, data, token []byte, n int) {
+	raw := data[:n]
+	i := tokenIndex(data, token)
+	if i >= 0 {
+		raw = data[i : i+len(token)]
+	} else {
+		i = 0
+	}
+
+	s.tokPos = advancePos(pos, data[:i])
+	s.tokEnd = advancePos(s.tokPos, raw)
+}
+
+// tokenIndex returns the index of token within data,
+// or -1 if token is not a sub-slice of data.
+func tokenIndex(data, token []byte) int {
+	if cap(token) == 0 {
+		return -1
+	}
+
+	i := cap(data) - cap(token)
+	if i < 0 || i > len(data) || len(token) > len(data)-i {
+		return -1
+	}
+	if &data[:i+1][i] != &token[:1][0] {
+		return -1
+	}
+	return i
+}
+
+// setErr records the first error encountered,
+// wrapping it in a *ScanError unless it is io.EOF.
 func (s *Scanner) setErr(err error) {
 	if s.err == nil || io.EOF == s.err {
+		if io.EOF != err {
+			err = &ScanError{Pos: s.pos, Token: s.tokens, Err: err}
+		}
 		s.err = err
 	}
 }
@@ -296,24 +862,145 @@ func (s *Scanner) setErr(err error) {
 // By default, Next uses an internal buffer and sets the
 // maximum token size to MaxScanTokenSize.
 //
-// Buffer panics if it is called after scanning has started.
+// Buffer panics if it is called after scanning has started, or on a
+// Scanner created by NewBytes or NewString, which needs no buffer.
 func (s *Scanner) Buffer(buf []byte, max int) {
 	if s.nextCalled {
 		panic("Buffer called after Next")
 	}
+	if s.inMemory {
+		panic("Buffer called on in-memory Scanner")
+	}
 	s.buf = buf[:cap(buf)]
 	s.maxTokenSize = max
 }
 
+// An OversizePolicy tells a Scanner what to do with a token that is
+// larger than the maximum token size.
+//
+// Except for OversizeFail, the policies cut the token into chunks that
+// fit in the buffer. When the buffer is full, the Scanner calls the split
+// function with atEOF set to find where the token starts, and takes as
+// much of it as it can while keeping back the last utf8.UTFMax-1 bytes of
+// the buffer, so that a delimiter of up to utf8.UTFMax bytes, like "\r\n"
+// or a multi-byte space, is never cut in two; for a longer separator given
+// to SplitOn, it keeps back one byte less than the separator. The next
+// chunk is the token the split function then returns, provided it starts
+// at the beginning of its data. If instead it starts later, or the split function skips input
+// without returning a token, the token ended with the previous chunk.
+// SplitLines and SplitWords work this way.
+type OversizePolicy int
+
+const (
+	// OversizeFail stops the scan with ErrTooLong. It is the default.
+	OversizeFail OversizePolicy = iota
+
+	// OversizeChunk delivers the token as a sequence of chunks, each
+	// returned by a call to Next, for which Partial reports true for all
+	// but the last. If the token ended with a chunk for which Partial
+	// reported true, an empty final chunk follows. The memory used stays
+	// bounded by the buffer however long the token is.
+	OversizeChunk
+
+	// OversizeTruncate delivers the first chunk of the token as if it
+	// were the whole token, and discards the rest.
+	OversizeTruncate
+
+	// OversizeSkip discards the token entirely.
+	OversizeSkip
+)
+
+// Oversize sets the policy for tokens larger than the maximum token size.
+// The default policy is OversizeFail.
+// The number of tokens the policy was applied to is reported by Oversized.
+//
+// Oversize panics if it is called after scanning has started.
+func (s *Scanner) Oversize(policy OversizePolicy) {
+	if s.nextCalled {
+		panic("Oversize called after Next")
+	}
+	s.oversize = policy
+}
+
+// Oversized returns the number of tokens larger than the maximum token
+// size that were chunked, truncated or skipped by the oversize policy.
+func (s *Scanner) Oversized() int { return s.oversized }
+
+// A RecoverFunc is called by a Scanner to recover from an error returned
+// by its split function. The arguments data and atEOF are as for
+// SplitFunc, with data starting where the failed split started, and cause
+// is the error. The return values are the number of bytes to skip, a flag,
+// done, reporting whether the input has been skipped to a point where
+// splitting can resume, and an error, if any, which stops the scan.
+//
+// If done is false, the Scanner skips the input, reads more data and calls
+// the function again, until it reports done or the input ends. The function
+// can thus skip to a delimiter even when it is not yet in the buffer.
+// Returning cause as the error aborts the scan as if there were no
+// recovery function.
+type RecoverFunc func(data []byte, atEOF bool, cause error) (advance int, done bool, err error)
+
+// Recover sets the function used to recover from errors returned by the
+// split function. By default, and if f is nil, such an error stops the
+// scan. Errors recovered from are reported by Recovered.
+// If a recovery skips no input at all, the scan stops with the error,
+// since splitting the same input again would fail again.
+func (s *Scanner) Recover(f RecoverFunc) {
+	s.recover = f
+}
+
+// Recovered returns the split errors that the Scanner recovered from,
+// in the order they occurred.
+func (s *Scanner) Recovered() []*ScanError { return s.recovered }
+
+// SkipPast returns a RecoverFunc that skips the input up to and including
+// the next occurrence of delim, or to the end of the input if there is none.
+func SkipPast(delim byte) RecoverFunc {
+	return func(data []byte, atEOF bool, cause error) (advance int, done bool, err error) {
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, true, nil
+		}
+		return len(data), atEOF, nil
+	}
+}
+
+// SkipN returns a RecoverFunc that skips n bytes of input,
+// or to the end of the input if it is shorter. The n bytes need not fit
+// in the buffer: the function counts the bytes skipped so far, so it must
+// not be shared between Scanners.
+func SkipN(n int) RecoverFunc {
+	skipped := 0
+	return func(data []byte, atEOF bool, cause error) (advance int, done bool, err error) {
+		advance = min(n-skipped, len(data))
+		skipped += advance
+		if skipped == n || atEOF {
+			skipped = 0
+			return advance, true, nil
+		}
+		return advance, false, nil
+	}
+}
+
 // Split sets the split function for the Scanner.
-// The default split function is ScanLines.
+// The default split function is SplitLines.
 //
-// Split panics if it is called after scanning has started.
+// Split may be called between calls to Next to change how the rest of the
+// input is split, for instance to read a line-delimited header and then a
+// length-delimited body. Data that has been read but not yet consumed is
+// kept and split by the new function.
+//
+// Split panics if it is called while tokens split by the old function are
+// held by Peek or Unread, or while a token is being delivered in chunks.
 func (s *Scanner) Split(split SplitFunc) {
-	if s.nextCalled {
-		panic("Split called after Next")
+	if len(s.ahead) > 0 {
+		panic("Split called with tokens read ahead")
+	}
+	if s.chunking {
+		panic("Split called in the middle of a chunked token")
 	}
 	s.split = split
+	s.splitState = nil
+	s.empties = 0
 }
 
 // Split functions
@@ -429,31 +1116,5 @@ func isSpace(r rune) bool {
 // never return an empty string. The definition of space is set by
 // unicode.IsSpace.
 func SplitWords(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	// Skip leading spaces.
-	start := 0
-	for (len(data)-start) >= utf8.UTFMax || utf8.FullRune(data[start:]) {
-		r, size := utf8.DecodeRune(data[start:])
-		if !isSpace(r) {
-			break
-		}
-		start += size
-	}
-
-	// Scan until space, marking end of word.
-	i := start
-	for (len(data)-i) >= utf8.UTFMax || utf8.FullRune(data[i:]) {
-		r, size := utf8.DecodeRune(data[i:])
-		if isSpace(r) {
-			return i + size, data[start:i], nil
-		}
-		i += size
-	}
-
-	// If we're at EOF, we have a final, non-empty, non-terminated word. Return it.
-	if atEOF && ((len(data)-start) >= utf8.UTFMax || utf8.FullRune(data[start:])) {
-		return len(data), data[start:], nil
-	}
-
-	// Request more data.
-	return start, nil, nil
+	return splitWords(data, atEOF, spaces)
 }
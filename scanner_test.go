@@ -10,8 +10,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"strings"
 	"testing"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
@@ -287,11 +289,162 @@ func TestScanLineTooLong(t *testing.T) {
 		lineNum++
 	}
 
-	if err := sc.Err(); ErrTooLong != err {
+	if err := sc.Err(); !errors.Is(err, ErrTooLong) {
 		t.Fatalf("expected ErrTooLong; got %v", err)
 	}
 }
 
+// collectChunks scans text with the given split function, joining the
+// chunks of oversized tokens.
+func collectChunks(t *testing.T, text string, split SplitFunc, max int) []string {
+	sc := New(&slowReader{max, strings.NewReader(text)})
+	sc.Split(split)
+	sc.MaxTokenSize(smallMaxTokenSize)
+	sc.Oversize(OversizeChunk)
+
+	var tokens []string
+	var token []byte
+	for sc.Next() {
+		if len(sc.Bytes()) > smallMaxTokenSize {
+			t.Fatalf("chunk of %d bytes is larger than the buffer", len(sc.Bytes()))
+		}
+		token = append(token, sc.Bytes()...)
+		if !sc.Partial() {
+			tokens = append(tokens, string(token))
+			token = token[:0]
+		}
+	}
+	if sc.Partial() {
+		t.Error("scan ended with a partial token")
+	}
+	if err := sc.Err(); err != nil {
+		t.Error(err)
+	}
+	return tokens
+}
+
+// Test that oversized tokens are delivered in chunks that join
+// to the tokens scanned with a large enough buffer.
+func TestOversizeChunk(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	pieces := []string{"\n", "\r\n", "\r", "\v", "\u2028", " ", "\u3000", "\t\t", "a", "¼", "日本語", "<SEP>>"}
+	for i := 0; i < 50; i++ {
+		var b strings.Builder
+		for b.Len() < 8*smallMaxTokenSize {
+			p := pieces[rng.Intn(len(pieces))]
+			if rng.Intn(4) == 0 {
+				p = strings.Repeat(p, rng.Intn(3*smallMaxTokenSize))
+			}
+			b.WriteString(p)
+		}
+		text := b.String()
+
+		splits := []SplitFunc{
+			SplitLines, SplitWords, SplitUniversalLines,
+			SplitOn([]byte("\u3000")), SplitOn([]byte("\r\n\r\n")),
+			SplitOn([]byte("<SEP>>")),
+		}
+		for _, split := range splits {
+			var want []string
+			sc := New(strings.NewReader(text))
+			sc.Split(split)
+			sc.Buffer(nil, len(text)+1)
+			for sc.Next() {
+				want = append(want, sc.Text())
+			}
+
+			for _, max := range []int{1, 7, 1000} {
+				got := collectChunks(t, text, split, max)
+				if len(want) != len(got) {
+					t.Fatalf("%d: got %d tokens; want %d", i, len(got), len(want))
+				}
+				for j := range want {
+					if want[j] != got[j] {
+						t.Fatalf("%d: token %d: got %.50q; want %.50q", i, j, got[j], want[j])
+					}
+				}
+			}
+		}
+	}
+}
+
+// Test that oversized tokens can be truncated or skipped.
+func TestOversizeTruncateSkip(t *testing.T) {
+	t.Parallel()
+
+	var short, long []string
+	for i := 0; i < 20; i++ {
+		short = append(short, strings.Repeat(string(rune('a'+i)), i))
+		long = append(long, strings.Repeat(string(rune('A'+i)), smallMaxTokenSize+1+50*i))
+	}
+
+	tests := []struct {
+		split SplitFunc
+		sep   string
+	}{
+		{SplitLines, "\n"},
+		{SplitLines, "\r\n"},
+		{SplitWords, " \t"},
+		{SplitUniversalLines, "\r\n"},
+		{SplitUniversalLines, "\u2028"},
+		{SplitOn([]byte("<SEP>>")), "<SEP>>"},
+	}
+	for _, test := range tests {
+		var tokens []string
+		for i := range short {
+			if i%3 == 0 {
+				tokens = append(tokens, long[i])
+			}
+			if test.sep != " \t" || short[i] != "" {
+				tokens = append(tokens, short[i])
+			}
+		}
+		text := strings.Join(tokens, test.sep)
+
+		for _, policy := range []OversizePolicy{OversizeTruncate, OversizeSkip} {
+			sc := New(&slowReader{5, strings.NewReader(text)})
+			sc.Split(test.split)
+			sc.MaxTokenSize(smallMaxTokenSize)
+			sc.Oversize(policy)
+
+			i, truncated := 0, 0
+			for sc.Next() {
+				if policy == OversizeSkip && len(tokens[i]) > smallMaxTokenSize {
+					i++
+				}
+				want := tokens[i]
+				if len(want) > smallMaxTokenSize {
+					// No separator may be cut in two between chunks.
+					want = want[:smallMaxTokenSize-(max(utf8.UTFMax, len(test.sep))-1)]
+					truncated++
+				}
+				if want != sc.Text() {
+					t.Errorf("%d: token %d: got %.20q (%d bytes); want %.20q (%d bytes)",
+						policy, i, sc.Text(), len(sc.Text()), want, len(want))
+				}
+				if sc.Partial() {
+					t.Errorf("%d: token %d is partial", policy, i)
+				}
+				i++
+			}
+			if len(tokens) != i {
+				t.Errorf("%d: got %d tokens; want %d", policy, i, len(tokens))
+			}
+			if want := len(long)/3 + 1; want != sc.Oversized() {
+				t.Errorf("%d: Oversized = %d; want %d", policy, sc.Oversized(), want)
+			}
+			if policy == OversizeSkip && truncated != 0 {
+				t.Errorf("%d: %d tokens truncated", policy, truncated)
+			}
+			if err := sc.Err(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
 // Test that the line splitter handles a final line without a newline.
 func testNoNewline(t *testing.T, text string, lines []string) {
 	r := strings.NewReader(text)
@@ -411,11 +564,116 @@ func TestSplitError(t *testing.T) {
 	if okCount != i {
 		t.Errorf("unexpected termination; expected %d tokens got %d", okCount, i)
 	}
-	if err := sc.Err(); errTest != err {
+	if err := sc.Err(); !errors.Is(err, errTest) {
 		t.Fatalf("expected %v got %v", errTest, err)
 	}
 }
 
+// numberSplit is a split function for lines of decimal digits that
+// returns errTest as soon as it sees any other line.
+func numberSplit(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for _, c := range data {
+		if c == '\n' {
+			break
+		}
+		if c < '0' || c > '9' {
+			return 0, nil, errTest
+		}
+	}
+	return SplitLines(data, atEOF)
+}
+
+// Test that the Scanner can recover from split errors and resume scanning.
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	text := "1\n22\nbad\n333\n" + strings.Repeat("x", 3*smallMaxTokenSize) + "\n4444\nworse"
+	sc := New(&slowReader{5, strings.NewReader(text)})
+	sc.Split(numberSplit)
+	sc.MaxTokenSize(smallMaxTokenSize)
+	sc.Recover(SkipPast('\n'))
+
+	var got []string
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 22 333 4444"; want != strings.Join(got, " ") {
+		t.Errorf("got %q; want %q", strings.Join(got, " "), want)
+	}
+
+	want := []Position{
+		{Offset: 5, Line: 3, Column: 1},
+		{Offset: 13, Line: 5, Column: 1},
+		{Offset: int64(len(text) - 5), Line: 7, Column: 1},
+	}
+	errs := sc.Recovered()
+	if len(want) != len(errs) {
+		t.Fatalf("recovered from %d errors; want %d", len(errs), len(want))
+	}
+	for i, err := range errs {
+		if want[i] != err.Pos || !errors.Is(err, errTest) {
+			t.Errorf("error %d: got %v at %+v; want %v at %+v", i, err.Err, err.Pos, errTest, want[i])
+		}
+	}
+}
+
+// Test recovery by skipping bytes, and aborting from a recovery function.
+func TestRecoverSkipN(t *testing.T) {
+	t.Parallel()
+
+	sc := New(strings.NewReader("12\nab\n34\nc\n"))
+	sc.Split(numberSplit)
+	sc.Recover(SkipN(3))
+
+	var got []string
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "12 34"; want != strings.Join(got, " ") {
+		t.Errorf("got %q; want %q", strings.Join(got, " "), want)
+	}
+	if len(sc.Recovered()) != 2 {
+		t.Errorf("recovered from %d errors; want 2", len(sc.Recovered()))
+	}
+
+	// Skip more bytes than fit in the buffer.
+	text := "12\n" + strings.Repeat("x", 3*smallMaxTokenSize) + "\n34\n" + strings.Repeat("y", 3*smallMaxTokenSize) + "\n56"
+	sc = New(&slowReader{5, strings.NewReader(text)})
+	sc.Split(numberSplit)
+	sc.MaxTokenSize(smallMaxTokenSize)
+	sc.Recover(SkipN(3*smallMaxTokenSize + 1))
+	got = got[:0]
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "12 34 56"; want != strings.Join(got, " ") {
+		t.Errorf("got %q; want %q", strings.Join(got, " "), want)
+	}
+
+	sc = New(strings.NewReader("12\nab\n34\n"))
+	sc.Split(numberSplit)
+	sc.Recover(func(data []byte, atEOF bool, cause error) (int, bool, error) {
+		return 0, false, cause
+	})
+	for sc.Next() {
+	}
+	if err := sc.Err(); !errors.Is(err, errTest) {
+		t.Errorf("Err = %v; want %v", err, errTest)
+	}
+	if len(sc.Recovered()) != 0 {
+		t.Errorf("recovered from %d errors; want 0", len(sc.Recovered()))
+	}
+}
+
 // Test that an EOF is overridden by a user-generated scan error.
 func TestErrAtEOF(t *testing.T) {
 	t.Parallel()
@@ -436,11 +694,40 @@ func TestErrAtEOF(t *testing.T) {
 
 	for sc.Next() {
 	}
-	if errTest != sc.Err() {
+	if !errors.Is(sc.Err(), errTest) {
 		t.Error("wrong error:", sc.Err())
 	}
 }
 
+// Test that errors are reported with the position where scanning stopped.
+func TestScanError(t *testing.T) {
+	t.Parallel()
+
+	text := "one\ntwo\n" + strings.Repeat("x", 2*smallMaxTokenSize) + "\n"
+	sc := New(strings.NewReader(text))
+	sc.MaxTokenSize(smallMaxTokenSize)
+	for sc.Next() {
+	}
+
+	err := sc.Err()
+	var se *ScanError
+	if !errors.As(err, &se) {
+		t.Fatalf("error %v is %T; want *ScanError", err, err)
+	}
+	if !errors.Is(err, ErrTooLong) {
+		t.Errorf("errors.Is(%v, ErrTooLong) = false", err)
+	}
+	if want := (Position{Offset: 8, Line: 3, Column: 1}); want != se.Pos {
+		t.Errorf("Pos = %+v; want %+v", se.Pos, want)
+	}
+	if se.Token != 2 {
+		t.Errorf("Token = %d; want 2", se.Token)
+	}
+	if want := "line 3: " + ErrTooLong.Error(); want != err.Error() {
+		t.Errorf("Error() = %q; want %q", err.Error(), want)
+	}
+}
+
 // Test for issue 5268.
 type alwaysError struct{}
 
@@ -455,7 +742,7 @@ func TestNonEOFWithEmptyRead(t *testing.T) {
 	for sc.Next() {
 		t.Fatal("read should fail")
 	}
-	if err := sc.Err(); io.ErrUnexpectedEOF != err {
+	if err := sc.Err(); !errors.Is(err, io.ErrUnexpectedEOF) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
@@ -474,7 +761,7 @@ func TestBadReader(t *testing.T) {
 	for sc.Next() {
 		t.Fatal("read should fail")
 	}
-	if err := sc.Err(); io.ErrNoProgress != err {
+	if err := sc.Err(); !errors.Is(err, io.ErrNoProgress) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
@@ -676,7 +963,7 @@ func TestNegativeEOFReader(t *testing.T) {
 			break
 		}
 	}
-	if err := sc.Err(); ErrBadReadCount != err {
+	if err := sc.Err(); !errors.Is(err, ErrBadReadCount) {
 		t.Errorf("scanner.Err: got %v, want %v", err, ErrBadReadCount)
 	}
 }
@@ -697,7 +984,7 @@ func TestLargeReader(t *testing.T) {
 	sc := New(largeReader{})
 	for sc.Next() {
 	}
-	if err := sc.Err(); ErrBadReadCount != err {
+	if err := sc.Err(); !errors.Is(err, ErrBadReadCount) {
 		t.Errorf("scanner.Err: got %v, want %v", err, ErrBadReadCount)
 	}
 }
@@ -725,6 +1012,254 @@ func TestReset(t *testing.T) {
 	}
 }
 
+// posAt returns the position of offset off in text.
+func posAt(text string, off int) Position {
+	line := 1 + strings.Count(text[:off], "\n")
+	col := off - strings.LastIndex(text[:off], "\n")
+	return Position{Offset: int64(off), Line: line, Column: col}
+}
+
+// Test that Pos and Span track tokens across buffer shifts and resizes.
+func TestPos(t *testing.T) {
+	t.Parallel()
+
+	text := "abc def\n\n  ghi\r\n" + strings.Repeat("x", smallMaxTokenSize-10) + "\n\tjkl mno  \npqr"
+	tests := []struct {
+		name  string
+		split SplitFunc
+		delim string // Skipped after each token.
+	}{
+		{"lines", SplitLines, "\n"},
+		{"words", SplitWords, ""},
+		{"bytes", SplitBytes, ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, max := range []int{1, 3, 100} {
+				sc := New(&slowReader{max, strings.NewReader(text)})
+				sc.Split(test.split)
+				sc.MaxTokenSize(smallMaxTokenSize)
+
+				from := 0
+				for sc.Next() {
+					tok := sc.Text()
+					off := from + strings.Index(text[from:], tok)
+					start, end := sc.Span()
+					if want := posAt(text, off); want != sc.Pos() || want != start {
+						t.Errorf("%d: token %q: pos %+v; want %+v", max, tok, sc.Pos(), want)
+					}
+					if want := posAt(text, off+len(tok)); want != end {
+						t.Errorf("%d: token %q: end %+v; want %+v", max, tok, end, want)
+					}
+					from = off + len(tok)
+					if test.delim != "" {
+						from += strings.Index(text[from:], test.delim) + len(test.delim)
+					}
+				}
+				if err := sc.Err(); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+// Test the span of tokens that are not at the start of the data
+// or not taken from the data at all.
+func TestSpanForeignToken(t *testing.T) {
+	t.Parallel()
+
+	const text = "a\nb[cd]e\x81f"
+	sc := New(strings.NewReader(text))
+	sc.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(data) > 1 && data[1] == '[' {
+			j := bytes.IndexByte(data, ']')
+			return j + 1, data[2:j], nil
+		}
+		return SplitRunes(data, atEOF)
+	})
+
+	for _, want := range []struct {
+		tok        string
+		start, end Position
+	}{
+		{"a", Position{0, 1, 1}, Position{1, 1, 2}},
+		{"\n", Position{1, 1, 2}, Position{2, 2, 1}},
+		{"cd", Position{4, 2, 3}, Position{6, 2, 5}},
+		{"e", Position{7, 2, 6}, Position{8, 2, 7}},
+		{"\uFFFD", Position{8, 2, 7}, Position{9, 2, 8}},
+		{"f", Position{9, 2, 8}, Position{10, 2, 9}},
+	} {
+		if !sc.Next() {
+			t.Fatalf("scan stopped early: %v", sc.Err())
+		}
+		start, end := sc.Span()
+		if want.tok != sc.Text() || want.start != start || want.end != end {
+			t.Errorf("got %q %v-%v; want %q %v-%v", sc.Text(), start, end, want.tok, want.start, want.end)
+		}
+	}
+}
+
+// Test that Peek and Unread look ahead without losing tokens,
+// even as the buffer is reused.
+func TestPeekUnread(t *testing.T) {
+	t.Parallel()
+
+	var words []string
+	var offsets []int
+	text := ""
+	for i := 0; i < 200; i++ {
+		words = append(words, strings.Repeat(string(rune('a'+i%26)), i%50))
+		offsets = append(offsets, len(text))
+		text += words[i] + "\n"
+	}
+	sc := New(&slowReader{3, strings.NewReader(text)})
+	sc.MaxTokenSize(smallMaxTokenSize)
+
+	if err := sc.Unread(); ErrInvalidUnread != err {
+		t.Errorf("Unread before Next = %v; want %v", err, ErrInvalidUnread)
+	}
+	if peeked := sc.PeekN(-1); peeked != nil {
+		t.Errorf("PeekN(-1) = %q; want nil", peeked)
+	}
+
+	for i := 0; i < len(words); i++ {
+		if !sc.Next() {
+			t.Fatalf("%d: scan stopped early: %v", i, sc.Err())
+		}
+		cur := sc.Text()
+		if words[i] != cur {
+			t.Fatalf("%d: got %q; want %q", i, cur, words[i])
+		}
+
+		k := i % 4
+		peeked := sc.PeekN(k)
+		if want := min(k, len(words)-i-1); want != len(peeked) {
+			t.Fatalf("%d: peeked %d tokens; want %d", i, len(peeked), want)
+		}
+		for j, tok := range peeked {
+			if words[i+1+j] != string(tok) {
+				t.Errorf("%d: peeked %q; want %q", i, tok, words[i+1+j])
+			}
+		}
+		if next, ok := sc.Peek(); ok && words[i+1] != string(next) {
+			t.Errorf("%d: Peek = %q; want %q", i, next, words[i+1])
+		}
+		if cur != sc.Text() || posAt(text, offsets[i]) != sc.Pos() {
+			t.Errorf("%d: current token changed to %q at %v", i, sc.Text(), sc.Pos())
+		}
+
+		if i%3 == 0 {
+			if err := sc.Unread(); err != nil {
+				t.Fatalf("%d: Unread: %v", i, err)
+			}
+			if err := sc.Unread(); ErrInvalidUnread != err {
+				t.Errorf("%d: second Unread = %v; want %v", i, err, ErrInvalidUnread)
+			}
+			if !sc.Next() || words[i] != sc.Text() {
+				t.Fatalf("%d: after Unread got %q; want %q", i, sc.Text(), words[i])
+			}
+		}
+	}
+
+	if sc.Next() {
+		t.Errorf("scan ran too long, got %q", sc.Text())
+	}
+	if _, ok := sc.Peek(); ok {
+		t.Error("Peek at end of input returned a token")
+	}
+	if err := sc.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test that the split function can be changed between tokens
+// without losing buffered data.
+func TestSplitMidStream(t *testing.T) {
+	t.Parallel()
+
+	text := "one two\nthree four\nfive six"
+	for _, max := range []int{1, 4, 100} {
+		sc := New(&slowReader{max, strings.NewReader(text)})
+
+		var got []string
+		for i := 0; sc.Next(); i++ {
+			got = append(got, sc.Text())
+			if i%2 == 0 {
+				sc.Split(SplitWords)
+			} else {
+				sc.Split(SplitLines)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if want := "one two|three|four|five|six"; want != strings.Join(got, "|") {
+			t.Errorf("%d: got %q; want %q", max, strings.Join(got, "|"), want)
+		}
+	}
+}
+
+// Test that Split panics when tokens split by the old function are pending.
+func TestSplitWithLookahead(t *testing.T) {
+	t.Parallel()
+
+	sc := New(strings.NewReader("a\nb\n"))
+	sc.Next()
+	sc.Peek()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Split did not panic")
+		}
+	}()
+	sc.Split(SplitWords)
+}
+
+// Test that the rest of the input can be read after scanning a header,
+// and that scanning resumes after the bytes read.
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	const text = "header 1\nheader 2\n\n{\"a\": 1}\n{\"b\": 2}\nline 1\nline 2\n"
+	sc := New(&slowReader{7, strings.NewReader(text)})
+	for sc.Next() && sc.Text() != "" {
+	}
+	if n := sc.Buffered(); n > 7 {
+		t.Errorf("Buffered = %d; want at most 7", n)
+	}
+
+	body := make([]byte, len(`{"a": 1}`+"\n"+`{"b": 2}`+"\n"))
+	if _, err := io.ReadFull(sc.Reader(), body); err != nil {
+		t.Fatal(err)
+	}
+	if want := text[strings.Index(text, "{"):][:len(body)]; want != string(body) {
+		t.Errorf("body = %q; want %q", body, want)
+	}
+
+	if !sc.Next() || sc.Text() != "line 1" {
+		t.Fatalf("after Reader got %q, %v; want \"line 1\"", sc.Text(), sc.Err())
+	}
+	if want := (Position{Offset: int64(strings.Index(text, "line 1")), Line: 6, Column: 1}); want != sc.Pos() {
+		t.Errorf("Pos = %+v; want %+v", sc.Pos(), want)
+	}
+
+	rest, err := io.ReadAll(sc.Reader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "line 2\n" {
+		t.Errorf("rest = %q; want %q", rest, "line 2\n")
+	}
+	if sc.Next() {
+		t.Errorf("scan ran too long, got %q", sc.Text())
+	}
+}
+
 func TestTextAllocs(t *testing.T) {
 	r := strings.NewReader("       foo       foo        42        42        42        42        42        42        42        42       4.2       4.2       4.2       4.2\n")
 	sc := New(r)
@@ -747,6 +1282,75 @@ func TestTextAllocs(t *testing.T) {
 	}
 }
 
+// Test that in-memory scanners return the same tokens as reading scanners.
+func TestNewBytesString(t *testing.T) {
+	t.Parallel()
+
+	inputs := append(append([]string{}, scanTests...), wordScanTests...)
+	inputs = append(inputs, "abc\r\ndef\n\nghi", strings.Repeat("x", 2*MaxScanTokenSize))
+	for _, split := range []SplitFunc{SplitLines, SplitWords, SplitRunes} {
+		for _, text := range inputs {
+			var want []string
+			sc := New(strings.NewReader(text))
+			sc.Split(split)
+			sc.Buffer(nil, len(text)+1)
+			for sc.Next() {
+				want = append(want, sc.Text())
+			}
+
+			b := []byte(text)
+			for _, sc := range []*Scanner{NewBytes(b), NewString(text)} {
+				sc.Split(split)
+				var got []string
+				for sc.Next() {
+					got = append(got, sc.Text())
+				}
+				if err := sc.Err(); err != nil {
+					t.Error(err)
+				}
+				if strings.Join(want, "|") != strings.Join(got, "|") {
+					t.Errorf("%.20q: got %.50q; want %.50q", text, got, want)
+				}
+			}
+		}
+	}
+}
+
+// Test that in-memory scanners do not allocate per token.
+func TestNewBytesStringAllocs(t *testing.T) {
+	const line = "       foo       foo        42        4.2"
+	text := strings.Repeat(line+"\n", 200)
+
+	for _, split := range []SplitFunc{SplitLines, SplitUniversalLines} {
+		b := NewBytes([]byte(text))
+		b.Split(split)
+		allocs := testing.AllocsPerRun(100, func() {
+			if !b.Next() {
+				t.Fatal(b.Err())
+			}
+			_ = b.Bytes()
+		})
+		if allocs != 0 {
+			t.Errorf("NewBytes: got %v allocations per token; want 0", allocs)
+		}
+
+		s := NewString(text)
+		s.Split(split)
+		allocs = testing.AllocsPerRun(100, func() {
+			if !s.Next() {
+				t.Fatal(s.Err())
+			}
+			_ = s.Text()
+		})
+		if allocs != 0 {
+			t.Errorf("NewString: got %v allocations per token; want 0", allocs)
+		}
+		if line != s.Text() {
+			t.Errorf("token = %q; want %q", s.Text(), line)
+		}
+	}
+}
+
 type scannerInterface interface {
 	Buffer([]byte, int)
 
@@ -769,9 +1373,22 @@ func benchScanner(b *testing.B, bench bench) {
 	f2 := func(r io.Reader) scannerInterface {
 		return New(r)
 	}
+	f3 := func(r io.Reader) scannerInterface {
+		sc := New(r)
+		sc.ReadAhead(0)
+		return sc
+	}
 
-	for _, newFn := range []func(io.Reader) scannerInterface{f1, f2} {
-		b.Run(fmt.Sprintf("%T", newFn(nil)), func(b *testing.B) {
+	for _, f := range []struct {
+		name  string
+		newFn func(io.Reader) scannerInterface
+	}{
+		{fmt.Sprintf("%T", f1(nil)), f1},
+		{fmt.Sprintf("%T", f2(nil)), f2},
+		{fmt.Sprintf("%T+ReadAhead", f3(nil)), f3},
+	} {
+		newFn := f.newFn
+		b.Run(f.name, func(b *testing.B) {
 			if bench.setup != nil {
 				bench.setup(b)
 			}
@@ -904,3 +1521,47 @@ func BenchmarkSplitWords(b *testing.B) {
 		},
 	})
 }
+
+// spin busy-waits for d, a delay shorter than time.Sleep can measure.
+func spin(d time.Duration) {
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
+
+// delayReader blocks for delay in each read of at most 4096 bytes, like a
+// network connection. The delay should be long enough for time.Sleep to
+// be accurate, at least a millisecond.
+type delayReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (r delayReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.r.Read(p[:min(len(p), 4096)])
+}
+
+func BenchmarkSlowReader(b *testing.B) {
+	text := strings.Repeat("       foo       foo        42        42        42        42        42        42        42        42       4.2       4.2       4.2       4.2\n", 1000)
+	const delay = time.Millisecond
+
+	benchScanner(b, bench{
+		setup: func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+		},
+
+		perG: func(b *testing.B, newFn func(io.Reader) scannerInterface) {
+			sc := newFn(delayReader{strings.NewReader(text), delay})
+
+			for sc.Scan() {
+				// Process each line, newline included, so that the lines
+				// of a read take as long as the read, and reading ahead
+				// can at best halve the time.
+				spin(time.Duration(len(sc.Bytes())+1) * delay / 4096)
+			}
+			if err := sc.Err(); err != nil {
+				b.Fatal(err)
+			}
+		},
+	})
+}
@@ -0,0 +1,218 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// Errors returned by Seek and Search.
+var (
+	ErrNotSeeker      = errors.New("scanner.Scanner: reader is not an io.Seeker")
+	ErrNegativeOffset = errors.New("scanner.Scanner: negative offset")
+	ErrWhence         = errors.New("scanner.Scanner: invalid whence")
+)
+
+// Seek moves the Scanner to an offset in its input, so that the next call
+// to Next returns the first token that starts at or after it. The offset
+// is counted like the Offset of the positions reported by the Scanner,
+// and interpreted according to whence as by io.Seeker: io.SeekCurrent
+// means relative to the end of the input consumed for the current token,
+// as recorded by Checkpoint. Seek returns the new offset. The reader of
+// the Scanner must be an io.Seeker, unless the Scanner was created by
+// NewBytes, NewString or a mapping NewFile.
+//
+// To find the token, Seek splits the input from the byte before offset,
+// or from the start of the UTF-8 encoded rune it is part of, and drops the
+// first token if it starts before offset. As for Shards, this only works
+// for split functions that resynchronize on a delimiter.
+//
+// Seek discards buffered data, tokens read ahead by Peek and the current
+// token, and clears any error. Unless offset is 0, the positions reported
+// after Seek hold only offsets, as the line numbers are unknown.
+func (s *Scanner) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.consumed.Offset
+	case io.SeekEnd:
+		_, end, err := s.bounds()
+		if err != nil {
+			return 0, err
+		}
+		offset += end
+	default:
+		return 0, ErrWhence
+	}
+	return offset, s.seekToken(offset)
+}
+
+// seekToken moves the Scanner to the first token at or after offset.
+func (s *Scanner) seekToken(offset int64) error {
+	if offset <= 0 {
+		return s.seek(offset)
+	}
+
+	// Start at the byte before offset, or at the start of its rune.
+	var buf [2*utf8.UTFMax - 1]byte
+	k := min(offset, utf8.UTFMax)
+	if err := s.seek(offset - k); err != nil {
+		return err
+	}
+	n := copy(buf[:], s.buf[s.start:s.end])
+	if !s.inMemory {
+		n, _ = io.ReadFull(s.r, buf[:])
+		if _, err := s.r.(io.Seeker).Seek(int64(-n), io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+	i := k - 1
+	if n > int(i) {
+		i = int64(runeStart(buf[:n], int(i)))
+	}
+	if err := s.seek(offset - k + i); err != nil {
+		return err
+	}
+
+	if !s.scanToken(s.ctx) {
+		return s.Err()
+	}
+	if s.tokPos.Offset >= offset {
+		// Keep the token for Next.
+		s.ahead = append(s.ahead, s.save())
+		s.restore(lookahead{consumed: s.prior, index: s.index - 1})
+	} else {
+		s.tokens--
+		s.index--
+		s.restore(lookahead{consumed: s.consumed, index: s.index})
+	}
+	return nil
+}
+
+// seek moves the Scanner to offset, resetting its state.
+func (s *Scanner) seek(offset int64) error {
+	if offset < 0 {
+		return ErrNegativeOffset
+	}
+
+	if s.inMemory {
+		s.start = int(min(offset, int64(len(s.buf))))
+		s.end = len(s.buf)
+		s.err = io.EOF
+	} else {
+		base, err := s.base()
+		if err != nil {
+			return err
+		}
+		if _, err := s.r.(io.Seeker).Seek(base+offset, io.SeekStart); err != nil {
+			return err
+		}
+		s.start = 0
+		s.end = 0
+		s.err = nil
+	}
+
+	s.pos = Position{Offset: offset}
+	if offset == 0 {
+		s.pos = startPos
+	}
+	s.restore(lookahead{consumed: s.pos, index: s.index})
+	s.ahead = nil
+	s.canUnread = false
+	s.empties = 0
+	s.chunking = false
+	s.discard = false
+	s.recovering = nil
+	s.cancelErr = nil
+	s.done = false
+	return nil
+}
+
+// base returns the offset in the underlying reader, which must be an
+// io.Seeker, of the input at offset 0.
+func (s *Scanner) base() (int64, error) {
+	seeker, ok := s.r.(io.Seeker)
+	if !ok {
+		return 0, ErrNotSeeker
+	}
+
+	pending := 0
+	if s.pending != nil {
+		// Wait for the read in the background, which moved the reader.
+		res := <-s.pending
+		s.pending = nil
+		s.spare = res.buf
+		pending = max(res.n, 0)
+	}
+
+	cur, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return cur - s.pos.Offset - int64(s.end-s.start+pending), nil
+}
+
+// Search moves the Scanner, as Seek does, to the first token of its input
+// for which cmp returns zero or a positive number, so that the next call
+// to Next returns it. The tokens must be sorted by cmp: it returns a
+// negative number for the tokens before the one sought, and zero or a
+// positive number for that token and the ones after it, as the target
+// comparison function of slices.BinarySearchFunc does. If there is no
+// such token, the next call to Next returns false.
+//
+// Search does a binary search over the offsets of the input, reading only
+// the tokens at about log2 of the input size offsets.
+func (s *Scanner) Search(cmp func(token []byte) int) error {
+	lo, hi, err := s.bounds()
+	if err != nil {
+		return err
+	}
+
+	// Find the first offset whose token is at or after the one sought.
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if err := s.seekToken(mid); err != nil {
+			return err
+		}
+		if !s.Next() {
+			if err := s.Err(); err != nil {
+				return err
+			}
+			hi = mid
+		} else if cmp(s.token) >= 0 {
+			hi = mid
+		} else {
+			// No token starts between mid and this one.
+			lo = max(mid, s.tokPos.Offset) + 1
+		}
+	}
+	return s.seekToken(lo)
+}
+
+// bounds returns the offsets of the start and end of the input.
+func (s *Scanner) bounds() (start, end int64, err error) {
+	if s.inMemory {
+		return 0, int64(len(s.buf)), nil
+	}
+	base, err := s.base()
+	if err != nil {
+		return 0, 0, err
+	}
+	seeker := s.r.(io.Seeker)
+	cur, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := seeker.Seek(cur, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	return max(-base, 0), size - base, nil
+}
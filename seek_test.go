@@ -0,0 +1,164 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+type offsetToken struct {
+	offset int64
+	token  string
+}
+
+// offsetTokens returns the remaining tokens of sc with their offsets.
+func offsetTokens(sc *Scanner) []offsetToken {
+	var tokens []offsetToken
+	for sc.Next() {
+		tokens = append(tokens, offsetToken{sc.Pos().Offset, sc.Text()})
+	}
+	return tokens
+}
+
+// Test that after Seek, a Scanner returns the tokens that start at or
+// after the offset.
+func TestSeek(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		text := randomText(rng, rng.Intn(300))
+		for _, split := range []SplitFunc{SplitLines, SplitWords} {
+			sc := New(strings.NewReader(text))
+			sc.Split(split)
+			all := offsetTokens(sc)
+
+			for off := int64(0); off <= int64(len(text)); off++ {
+				sc := New(strings.NewReader(text))
+				if rng.Intn(2) == 0 {
+					sc = NewString(text)
+				}
+				sc.Split(split)
+				for n := rng.Intn(3); n > 0 && sc.Next(); n-- {
+				}
+				if rng.Intn(2) == 0 {
+					sc.Peek()
+				}
+
+				if got, err := sc.Seek(off, io.SeekStart); got != off || err != nil {
+					t.Fatalf("Seek(%d) = %d, %v", off, got, err)
+				}
+				var want []offsetToken
+				for _, tok := range all {
+					if tok.offset >= off {
+						want = append(want, tok)
+					}
+				}
+				if got := offsetTokens(sc); !slices.Equal(got, want) {
+					t.Fatalf("%d: text %q: after Seek(%d), got %v; want %v", i, text, off, got, want)
+				}
+				if err := sc.Err(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func TestSeekWhence(t *testing.T) {
+	const text = "one\ntwo\nthree\n"
+	sc := New(strings.NewReader(text))
+	sc.Next()
+	if off, err := sc.Seek(0, io.SeekCurrent); off != 4 || err != nil {
+		t.Fatalf("Seek(0, io.SeekCurrent) = %d, %v; want 4, nil", off, err)
+	}
+	if !sc.Next() || sc.Text() != "two" {
+		t.Fatalf("got %q; want %q", sc.Text(), "two")
+	}
+	if off, err := sc.Seek(-6, io.SeekEnd); off != 8 || err != nil {
+		t.Fatalf("Seek(-6, io.SeekEnd) = %d, %v; want 8, nil", off, err)
+	}
+	if !sc.Next() || sc.Text() != "three" {
+		t.Fatalf("got %q; want %q", sc.Text(), "three")
+	}
+	if pos := sc.Pos(); pos.IsValid() {
+		t.Errorf("Pos = %v; want no line", pos)
+	}
+	if _, err := sc.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Next() || sc.Text() != "one" || sc.Pos().Line != 1 {
+		t.Fatalf("got %q at %v; want %q at 1:1", sc.Text(), sc.Pos(), "one")
+	}
+
+	sc = New(struct{ io.Reader }{strings.NewReader(text)})
+	if _, err := sc.Seek(1, io.SeekStart); !errors.Is(err, ErrNotSeeker) {
+		t.Errorf("Seek = %v; want %v", err, ErrNotSeeker)
+	}
+	if err := sc.Search(func([]byte) int { return 0 }); !errors.Is(err, ErrNotSeeker) {
+		t.Errorf("Search = %v; want %v", err, ErrNotSeeker)
+	}
+}
+
+// Test Search over sorted lines against a linear search.
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		keys := make([]int, rng.Intn(200))
+		for j := range keys {
+			keys[j] = rng.Intn(1000)
+		}
+		slices.Sort(keys)
+		var b strings.Builder
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%d %s\n", k, strings.Repeat("x", rng.Intn(20)))
+		}
+		text := b.String()
+
+		for j := 0; j < 20; j++ {
+			target := rng.Intn(1100)
+			cmp := func(token []byte) int {
+				var k int
+				fmt.Sscan(string(token), &k)
+				return k - target
+			}
+
+			sc := New(strings.NewReader(text))
+			if j%2 == 0 {
+				sc = NewString(text)
+			}
+			if err := sc.Search(cmp); err != nil {
+				t.Fatal(err)
+			}
+			k, found := slices.BinarySearch(keys, target)
+			if !sc.Next() {
+				if k < len(keys) {
+					t.Fatalf("%d: no line for %d; want key %d", i, target, keys[k])
+				}
+				continue
+			}
+			var got int
+			fmt.Sscan(sc.Text(), &got)
+			if k == len(keys) || got != keys[k] {
+				t.Fatalf("%d: found %q for %d (found=%t)", i, sc.Text(), target, found)
+			}
+			// It is the first line with the key.
+			want := strings.Count(text[:sc.Pos().Offset], "\n")
+			if want != k {
+				t.Fatalf("%d: found line %d for %d; want line %d", i, want, target, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// A Range is the span of bytes from Start up to, but not including, End.
+type Range struct {
+	Start, End int64
+}
+
+// Len returns the number of bytes in the range.
+func (rg Range) Len() int64 { return rg.End - rg.Start }
+
+func (rg Range) String() string {
+	return fmt.Sprintf("[%d,%d)", rg.Start, rg.End)
+}
+
+// Shards cuts the size bytes of r into at most n ranges of about the same
+// length whose boundaries fall between the tokens of split. The ranges
+// cover the input in order, without gaps or overlaps, and none is empty,
+// so scanning each of them with NewRange yields every token of the input
+// exactly once.
+//
+// To find a boundary near an arbitrary offset, Shards splits the input
+// starting just before it and takes the end of the first token. This only
+// works for split functions that resynchronize on a delimiter, like
+// SplitLines and SplitWords: once a delimiter is seen, the tokens after it
+// must not depend on what came before.
+func Shards(r io.ReaderAt, size int64, n int, split SplitFunc) ([]Range, error) {
+	if n < 1 {
+		n = 1
+	}
+	shards := make([]Range, 0, n)
+	var start int64
+	for i := 1; i <= n; i++ {
+		end, err := boundary(r, size, size*int64(i)/int64(n), split)
+		if err != nil {
+			return nil, err
+		}
+		// Keep the ranges from overlapping even if the split function
+		// resynchronizes differently from nearby offsets.
+		if end > start {
+			shards = append(shards, Range{start, end})
+			start = end
+		}
+	}
+	return shards, nil
+}
+
+// NewRange returns a new Scanner to read the bytes of r in rg. Unless rg
+// starts at the beginning of the input, the positions of the Scanner hold
+// only offsets into r, as the line numbers are unknown.
+func NewRange(r io.ReaderAt, rg Range) *Scanner {
+	s := New(io.NewSectionReader(r, rg.Start, rg.Len()))
+	if rg.Start > 0 {
+		s.pos = Position{Offset: rg.Start}
+		s.consumed = s.pos
+	}
+	return s
+}
+
+// boundary returns the first token boundary at or after off in the size
+// bytes of r, found by splitting from the byte before off, or from the
+// start of the UTF-8 encoded rune it is part of, and taking the end of the
+// first token.
+func boundary(r io.ReaderAt, size, off int64, split SplitFunc) (int64, error) {
+	if off <= 0 {
+		return 0, nil
+	}
+	if off >= size {
+		return size, nil
+	}
+
+	var buf [2*utf8.UTFMax - 1]byte
+	k := min(off, utf8.UTFMax)
+	n, err := r.ReadAt(buf[:min(int64(len(buf)), size-off+k)], off-k)
+	if n < int(k) {
+		return 0, err
+	}
+
+	s := NewRange(r, Range{off - k + int64(runeStart(buf[:n], int(k-1))), size})
+	s.Split(split)
+	if !s.Next() {
+		if err := s.Err(); err != nil {
+			return 0, err
+		}
+		return size, nil
+	}
+	return s.pos.Offset, nil
+}
+
+// runeStart returns the index in b of the first byte of the UTF-8 encoded
+// rune holding b[i], or i if b[i] is not part of a valid encoding.
+func runeStart(b []byte, i int) int {
+	for j := i; j >= 0 && j > i-utf8.UTFMax; j-- {
+		if utf8.RuneStart(b[j]) {
+			if _, size := utf8.DecodeRune(b[j:]); j+size > i {
+				return j
+			}
+			break
+		}
+	}
+	return i
+}
@@ -0,0 +1,75 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// Test that scanning the ranges planned by Shards finds the same tokens at
+// the same offsets as a sequential Scanner.
+func TestShards(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		text := randomText(rng, rng.Intn(2000))
+		r := strings.NewReader(text)
+		for _, split := range []SplitFunc{SplitLines, SplitWords} {
+			var want []string
+			var wantOffsets []int64
+			sc := New(strings.NewReader(text))
+			sc.Split(split)
+			for sc.Next() {
+				want = append(want, sc.Text())
+				wantOffsets = append(wantOffsets, sc.Pos().Offset)
+			}
+
+			n := 1 + rng.Intn(50)
+			shards, err := Shards(r, int64(len(text)), n, split)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shards) > n {
+				t.Fatalf("%d: got %d shards; want at most %d", i, len(shards), n)
+			}
+
+			var got []string
+			var gotOffsets []int64
+			var end int64
+			for _, rg := range shards {
+				if rg.Start != end || rg.Len() <= 0 {
+					t.Fatalf("%d: bad shards %v", i, shards)
+				}
+				end = rg.End
+
+				sc := NewRange(r, rg)
+				sc.Split(split)
+				for sc.Next() {
+					got = append(got, sc.Text())
+					gotOffsets = append(gotOffsets, sc.Pos().Offset)
+				}
+				if err := sc.Err(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if end != int64(len(text)) {
+				t.Fatalf("%d: shards %v end at %d; want %d", i, shards, end, len(text))
+			}
+			if !slices.Equal(want, got) {
+				t.Fatalf("%d: got %d tokens %.100q; want %d tokens %.100q",
+					i, len(got), got, len(want), want)
+			}
+			if !slices.Equal(wantOffsets, gotOffsets) {
+				t.Fatalf("%d: got offsets %v; want %v", i, gotOffsets, wantOffsets)
+			}
+		}
+	}
+}
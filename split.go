@@ -0,0 +1,429 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A SplitOption changes the behavior of a split function built by SplitOn,
+// SplitAny or SplitOnFunc. Options are combined by passing several of them.
+type SplitOption int
+
+const (
+	// KeepSeparator keeps the separator at the end of each token that
+	// has one.
+	KeepSeparator SplitOption = 1 << iota
+
+	// EmptyFinalToken returns a final token at the end of the input even
+	// if it is empty, as when the input ends with a separator, by
+	// returning ErrFinalToken. Empty input then has one empty token.
+	EmptyFinalToken
+
+	// CollapseSeparators treats a run of separators as one and skips
+	// those at the start of the input, so that, as with SplitWords, no
+	// token is empty. EmptyFinalToken then has no effect, and
+	// KeepSeparator keeps only the first separator of a run. SplitOn
+	// ignores it.
+	CollapseSeparators
+)
+
+// splitOptions returns the options in opts combined.
+func splitOptions(opts []SplitOption) SplitOption {
+	var o SplitOption
+	for _, opt := range opts {
+		o |= opt
+	}
+	return o
+}
+
+// SplitOn returns a split function for a Scanner that returns the text
+// between occurrences of sep, which is removed unless KeepSeparator is
+// given. As with SplitLines, the last token is returned even if it has no
+// separator, but is not empty unless EmptyFinalToken is given. SplitOn
+// panics if sep is empty.
+//
+// When the Scanner asks for more data to complete a token, the split
+// function does not search again the bytes it has already inspected, but
+// only checks that the data still starts with them. To do so, it keeps
+// state from one call to the next, so it must not be shared between
+// Scanners or called from several goroutines at once, as a Parallel does.
+//
+// The last token is returned with ErrFinalToken or, if sep is longer than
+// utf8.UTFMax bytes, with an error wrapping it, which tells the Scanner
+// how much of the buffer to keep back so that no separator is cut in two
+// between the chunks of an oversized token. See OversizePolicy.
+func SplitOn(sep []byte, opts ...SplitOption) SplitFunc {
+	return splitOn(sep, nil, opts)
+}
+
+// splitOn is SplitOn, adding to *searched, if not nil, the number of bytes
+// searched for sep.
+func splitOn(sep []byte, searched *int, opts []SplitOption) SplitFunc {
+	if len(sep) == 0 {
+		panic("SplitOn: empty separator")
+	}
+	sep = bytes.Clone(sep)
+	o := splitOptions(opts)
+
+	final := ErrFinalToken
+	if len(sep) > utf8.UTFMax {
+		final = keepBack(len(sep) - 1)
+	}
+
+	// The data inspected the last time more data was requested, which
+	// holds no separator.
+	var scanned []byte
+
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		skip := 0
+		if len(scanned) > 0 && bytes.HasPrefix(data, scanned) {
+			// A separator may straddle the end of the bytes scanned.
+			skip = max(len(scanned)-len(sep)+1, 0)
+		} else {
+			scanned = scanned[:0]
+		}
+
+		i := bytes.Index(data[skip:], sep)
+		if searched != nil {
+			if i >= 0 {
+				*searched += i + len(sep)
+			} else {
+				*searched += len(data) - skip
+			}
+		}
+		if i >= 0 {
+			scanned = scanned[:0]
+			i += skip
+			if o&KeepSeparator != 0 {
+				return i + len(sep), data[:i+len(sep)], nil
+			}
+			return i + len(sep), data[:i], nil
+		}
+
+		if atEOF {
+			scanned = scanned[:0]
+			if len(data) > 0 || o&EmptyFinalToken != 0 {
+				return len(data), data, final
+			}
+			return 0, nil, nil
+		}
+
+		// Request more data.
+		scanned = append(scanned, data[len(scanned):]...)
+		return 0, nil, nil
+	}
+}
+
+// A keepBack is returned in place of ErrFinalToken by the split functions
+// of SplitOn whose separator is longer than utf8.UTFMax bytes, to tell the
+// Scanner how many bytes to keep back when it cuts a token into chunks.
+type keepBack int
+
+func (keepBack) Error() string { return ErrFinalToken.Error() }
+
+func (keepBack) Unwrap() error { return ErrFinalToken }
+
+// SplitAny returns a split function for a Scanner that returns the text
+// between the UTF-8 encoded runes of the input that are in set, as
+// strings.FieldsFunc does with CollapseSeparators, or as SplitOn would
+// with each of the runes otherwise.
+func SplitAny(set string, opts ...SplitOption) SplitFunc {
+	var ascii [utf8.RuneSelf]bool
+	for i := 0; i < len(set); i++ {
+		if set[i] >= utf8.RuneSelf {
+			return SplitOnFunc(func(r rune) bool {
+				return strings.ContainsRune(set, r)
+			}, opts...)
+		}
+		ascii[set[i]] = true
+	}
+	return SplitOnFunc(func(r rune) bool {
+		return r < utf8.RuneSelf && ascii[r]
+	}, opts...)
+}
+
+// SplitOnFunc returns a split function for a Scanner that returns the text
+// between the UTF-8 encoded runes of the input for which isSep returns
+// true. See SplitAny. A rune cut in two at the end of the data is
+// completed with more data before it is passed to isSep.
+func SplitOnFunc(isSep func(rune) bool, opts ...SplitOption) SplitFunc {
+	o := splitOptions(opts)
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		start := 0
+		if o&CollapseSeparators != 0 {
+			// Skip leading separators.
+			for haveRune(data[start:], atEOF) {
+				r, size := decodeRune(data[start:])
+				if !isSep(r) {
+					break
+				}
+				start += size
+			}
+		}
+
+		// Scan until a separator, marking the end of the token.
+		for i := start; haveRune(data[i:], atEOF); {
+			r, size := decodeRune(data[i:])
+			if isSep(r) {
+				if o&KeepSeparator != 0 {
+					return i + size, data[start : i+size], nil
+				}
+				return i + size, data[start:i], nil
+			}
+			i += size
+		}
+
+		// If we're at EOF, we have a final, non-terminated token.
+		if atEOF {
+			if o&(CollapseSeparators|EmptyFinalToken) == EmptyFinalToken {
+				return len(data), data[start:], ErrFinalToken
+			}
+			if len(data) > start {
+				return len(data), data[start:], nil
+			}
+		}
+
+		// Request more data.
+		return start, nil, nil
+	}
+}
+
+// haveRune reports whether data starts with a complete UTF-8 encoded rune,
+// or with what is left of one at EOF.
+func haveRune(data []byte, atEOF bool) bool {
+	return len(data) >= utf8.UTFMax || utf8.FullRune(data) || atEOF && len(data) > 0
+}
+
+// decodeRune is utf8.DecodeRune with a fast path for ASCII.
+func decodeRune(data []byte) (rune, int) {
+	if data[0] < utf8.RuneSelf {
+		return rune(data[0]), 1
+	}
+	return utf8.DecodeRune(data)
+}
+
+// SplitUniversalLines is a split function for a Scanner that returns each
+// line of text, stripped of its end-of-line marker, like SplitLines, but
+// recognizing all the mandatory line breaks of Unicode: \r\n, \n, \r, \v,
+// \f, NEL (U+0085), LS (U+2028) and PS (U+2029). A \r at the end of the
+// data is only taken as a line break once the next byte is known not to
+// be \n. To learn which marker ended each line, use a LineSplitter.
+func SplitUniversalLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, n := universalLine(data, atEOF)
+	if advance == 0 {
+		return 0, nil, nil
+	}
+	return advance, data[:n], nil
+}
+
+// SplitRawLines is a split function for a Scanner that returns each line
+// of text with its end-of-line marker, \n or \r\n, left in place. The last
+// line of input is returned as is even if it has no newline, so the
+// tokens put back together are exactly the input.
+func SplitRawLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		// We have a full newline-terminated line.
+		return i + 1, data[:i+1], nil
+	}
+
+	// If we're at EOF, we have a final, non-terminated line. Return it.
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	// Request more data.
+	return 0, nil, nil
+}
+
+// A LineSplitter splits lines like SplitUniversalLines, and records the
+// end-of-line marker of the last line it split.
+type LineSplitter struct {
+	term string
+}
+
+// Split is a split function for a Scanner that returns each line of text
+// like SplitUniversalLines.
+func (l *LineSplitter) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, n := universalLine(data, atEOF)
+	if advance == 0 {
+		return 0, nil, nil
+	}
+	l.term = terminator(data[n:advance])
+	return advance, data[:n], nil
+}
+
+// Terminator returns the end-of-line marker of the last line split, or ""
+// if it had none. Tokens read ahead by Scanner.Peek are split before they
+// are returned by Next, so Terminator is then that of the last one.
+func (l *LineSplitter) Terminator() string { return l.term }
+
+// unicodeBreaks are the multi-byte line breaks other than \r\n.
+var unicodeBreaks = [...]string{"\u0085", "\u2028", "\u2029"}
+
+// universalLine returns the number of bytes of data taken by its first
+// line, and the length of the line without its end-of-line marker, or
+// zero if more data is needed.
+func universalLine(data []byte, atEOF bool) (advance, n int) {
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\n', '\v', '\f':
+			return i + 1, i
+		case '\r':
+			if i+1 < len(data) {
+				if data[i+1] == '\n' {
+					return i + 2, i
+				}
+				return i + 1, i
+			}
+			if !atEOF {
+				// The next byte may be \n.
+				return 0, 0
+			}
+			return i + 1, i
+		case 0xC2, 0xE2:
+			for _, sep := range unicodeBreaks {
+				rest := data[i:]
+				if len(rest) >= len(sep) {
+					if string(rest[:len(sep)]) == sep {
+						return i + len(sep), i
+					}
+				} else if !atEOF && sep[:len(rest)] == string(rest) {
+					// The marker may be cut in two.
+					return 0, 0
+				}
+			}
+		}
+	}
+
+	// If we're at EOF, we have a final, non-terminated line. Return it.
+	if atEOF && len(data) > 0 {
+		return len(data), len(data)
+	}
+
+	// Request more data.
+	return 0, 0
+}
+
+// terminator returns the end-of-line marker t as a string, without
+// allocating.
+func terminator(t []byte) string {
+	if len(t) == 1 {
+		return string(t)
+	}
+	if string(t) == "\r\n" {
+		return "\r\n"
+	}
+	for _, sep := range unicodeBreaks {
+		if string(t) == sep {
+			return sep
+		}
+	}
+	return string(t)
+}
+
+// wordSeps tells splitWords which runes separate words.
+type wordSeps struct {
+	ascii [utf8.RuneSelf]bool // Whether each ASCII rune is a separator.
+	isSep func(rune) bool     // Whether a non-ASCII rune is a separator.
+}
+
+func newWordSeps(isSep func(rune) bool) *wordSeps {
+	w := &wordSeps{isSep: isSep}
+	for r := range w.ascii {
+		w.ascii[r] = isSep(rune(r))
+	}
+	return w
+}
+
+func (w *wordSeps) is(r rune) bool {
+	if r < utf8.RuneSelf {
+		return w.ascii[r]
+	}
+	return w.isSep(r)
+}
+
+var (
+	spaces         = newWordSeps(isSpace)
+	asciiSpaces    = newWordSeps(isASCIISpace)
+	spacesAndPunct = newWordSeps(isSpaceOrPunct)
+)
+
+// SplitWordsFunc returns a split function for a Scanner that returns each
+// word of text separated by the runes for which isSep returns true, like
+// SplitWords does for spaces.
+func SplitWordsFunc(isSep func(rune) bool) SplitFunc {
+	seps := newWordSeps(isSep)
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		return splitWords(data, atEOF, seps)
+	}
+}
+
+// isASCIISpace reports whether the character is an ASCII white space
+// character.
+func isASCIISpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// SplitWordsASCII is a split function for a Scanner like SplitWords, but
+// only the ASCII white space characters, space, \t, \n, \v, \f and \r,
+// separate words.
+func SplitWordsASCII(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return splitWords(data, atEOF, asciiSpaces)
+}
+
+// isSpaceOrPunct reports whether the character is a Unicode white space or
+// punctuation character.
+func isSpaceOrPunct(r rune) bool {
+	return isSpace(r) || unicode.IsPunct(r)
+}
+
+// SplitWordsPunct is a split function for a Scanner like SplitWords, but
+// punctuation, as defined by unicode.IsPunct, also separates words.
+func SplitWordsPunct(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return splitWords(data, atEOF, spacesAndPunct)
+}
+
+// splitWords returns the next word of data separated by seps.
+func splitWords(data []byte, atEOF bool, seps *wordSeps) (advance int, token []byte, err error) {
+	// Skip leading spaces.
+	start := 0
+	for (len(data)-start) >= utf8.UTFMax || utf8.FullRune(data[start:]) {
+		r, size := utf8.DecodeRune(data[start:])
+		if !seps.is(r) {
+			break
+		}
+		start += size
+	}
+
+	// Scan until space, marking end of word.
+	i := start
+	for (len(data)-i) >= utf8.UTFMax || utf8.FullRune(data[i:]) {
+		r, size := utf8.DecodeRune(data[i:])
+		if seps.is(r) {
+			return i + size, data[start:i], nil
+		}
+		i += size
+	}
+
+	// If we're at EOF, we have a final, non-empty, non-terminated word. Return it.
+	if atEOF && ((len(data)-start) >= utf8.UTFMax || utf8.FullRune(data[start:])) {
+		return len(data), data[start:], nil
+	}
+
+	// Request more data.
+	return start, nil, nil
+}
@@ -0,0 +1,351 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"unicode"
+
+	. "github.com/weiwenchen2022/scanner"
+)
+
+// Test SplitOn against strings.Split, reading a few bytes at a time.
+func TestSplitOn(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	seps := []string{"\x1e", "\r\n\r\n", "---\n", "aab", "aa"}
+	pieces := []string{"a", "b", "-", "\r", "\n", "\x1e", "日本語"}
+	for i := 0; i < 500; i++ {
+		sep := seps[i%len(seps)]
+		var b strings.Builder
+		for n := rng.Intn(100); n > 0; n-- {
+			if rng.Intn(10) == 0 {
+				b.WriteString(sep)
+			} else {
+				b.WriteString(pieces[rng.Intn(len(pieces))])
+			}
+		}
+		text := b.String()
+
+		for _, opts := range [][]SplitOption{nil, {KeepSeparator}, {EmptyFinalToken}, {KeepSeparator, EmptyFinalToken}} {
+			want := strings.Split(text, sep)
+			if slices.Contains(opts, KeepSeparator) {
+				for j := range want[:len(want)-1] {
+					want[j] += sep
+				}
+			}
+			if !slices.Contains(opts, EmptyFinalToken) && want[len(want)-1] == "" {
+				want = want[:len(want)-1]
+			}
+
+			sc := New(&slowReader{1 + rng.Intn(10), strings.NewReader(text)})
+			sc.Split(SplitOn([]byte(sep), opts...))
+			var got []string
+			for sc.Next() {
+				got = append(got, sc.Text())
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("%d: SplitOn(%q, %v) of %q: got %q; want %q", i, sep, opts, text, got, want)
+			}
+		}
+	}
+}
+
+// Test that SplitOn does not search again the bytes it inspected before
+// asking for more data, even as the Scanner shifts and grows its buffer.
+func TestSplitOnNoRescan(t *testing.T) {
+	t.Parallel()
+
+	searched := 0
+	split := SplitOnCounting([]byte("--"), &searched)
+	if advance, token, _ := split([]byte("abcd-"), false); advance != 0 || token != nil {
+		t.Fatalf("got %d, %q; want more data", advance, token)
+	}
+	advance, token, _ := split([]byte("abcd--e"), false)
+	if advance != 6 || string(token) != "abcd" {
+		t.Errorf("got %d, %q; want 6, %q", advance, token, "abcd")
+	}
+	if searched != 7 {
+		t.Errorf("searched %d bytes; want 7", searched)
+	}
+
+	// The data of a call not starting with the bytes inspected is searched
+	// in full.
+	searched = 0
+	split([]byte("abcd-"), false)
+	split([]byte("a--d--e"), false)
+	if searched != 8 {
+		t.Errorf("searched %d bytes; want 8", searched)
+	}
+
+	const sep = "\r\n\r\n"
+	tokens := []string{strings.Repeat("a", 10000), "b", strings.Repeat("c", 5000), "d"}
+	text := strings.Join(tokens, sep)
+	searched, calls := 0, 0
+	split = SplitOnCounting([]byte(sep), &searched)
+	sc := New(&slowReader{100, strings.NewReader(text)})
+	sc.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		calls++
+		return split(data, atEOF)
+	})
+	var got []string
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, tokens) {
+		t.Errorf("got %d tokens; want %d", len(got), len(tokens))
+	}
+	if max := len(text) + calls*(len(sep)-1); searched > max {
+		t.Errorf("searched %d bytes in %d calls; want at most %d", searched, calls, max)
+	}
+}
+
+// Test that SplitOn starts afresh after Reset, even if the last scan
+// stopped while it was waiting for more data.
+func TestSplitOnReset(t *testing.T) {
+	t.Parallel()
+
+	sc := New(strings.NewReader(strings.Repeat("x", 40) + ",y"))
+	sc.Split(SplitOn([]byte(",")))
+	sc.Buffer(make([]byte, 16), 16)
+	for sc.Next() {
+	}
+	if err := sc.Err(); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("got error %v; want ErrTooLong", err)
+	}
+
+	const text = "a,b,c,d,e,f,g,h,i,j"
+	sc.Reset(strings.NewReader(text))
+	var got []string
+	for sc.Next() {
+		got = append(got, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Split(text, ","); !slices.Equal(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+// fieldsFunc splits text around each rune for which isSep returns true,
+// keeping the separators if keep is set.
+func fieldsFunc(text string, isSep func(rune) bool, keep bool) []string {
+	fields := []string{""}
+	for _, r := range text {
+		if isSep(r) {
+			if keep {
+				fields[len(fields)-1] += string(r)
+			}
+			fields = append(fields, "")
+		} else {
+			fields[len(fields)-1] += string(r)
+		}
+	}
+	return fields
+}
+
+// Test SplitAny and SplitOnFunc against strings.FieldsFunc and fieldsFunc,
+// reading a few bytes at a time so that runes are cut in two.
+func TestSplitAny(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	sets := []string{",;|", "　、", ",　"}
+	pieces := []string{"a", "bc", ",", ";", "|", "　", "、", "日本語", "¼"}
+	for i := 0; i < 500; i++ {
+		set := sets[i%len(sets)]
+		isSep := func(r rune) bool { return strings.ContainsRune(set, r) }
+		var b strings.Builder
+		for n := rng.Intn(30); n > 0; n-- {
+			b.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		text := b.String()
+
+		for _, opts := range [][]SplitOption{
+			nil, {KeepSeparator}, {EmptyFinalToken}, {KeepSeparator, EmptyFinalToken},
+			{CollapseSeparators}, {CollapseSeparators, EmptyFinalToken},
+		} {
+			var want []string
+			if slices.Contains(opts, CollapseSeparators) {
+				want = strings.FieldsFunc(text, isSep)
+			} else {
+				want = fieldsFunc(text, isSep, slices.Contains(opts, KeepSeparator))
+				if !slices.Contains(opts, EmptyFinalToken) && want[len(want)-1] == "" {
+					want = want[:len(want)-1]
+				}
+			}
+
+			for j, split := range []SplitFunc{SplitAny(set, opts...), SplitOnFunc(isSep, opts...)} {
+				sc := New(&slowReader{1 + rng.Intn(5), strings.NewReader(text)})
+				sc.Split(split)
+				var got []string
+				for sc.Next() {
+					got = append(got, sc.Text())
+				}
+				if err := sc.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("%d.%d: split %q on %q with %v: got %q; want %q", i, j, text, set, opts, got, want)
+				}
+			}
+		}
+	}
+}
+
+// Test SplitUniversalLines and LineSplitter with all line breaks, reading
+// a few bytes at a time so that the breaks are cut in two.
+func TestSplitUniversalLines(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	breaks := []string{"\r\n", "\n", "\r", "\v", "\f", "\u0085", "\u2028", "\u2029"}
+	pieces := []string{"a", "bc", "日本語", "\u2027", "\u00a0", " "}
+	for i := 0; i < 500; i++ {
+		var b strings.Builder
+		var lines, terms []string
+		for n := rng.Intn(20); n > 0; n-- {
+			var line strings.Builder
+			for m := rng.Intn(4); m > 0; m-- {
+				line.WriteString(pieces[rng.Intn(len(pieces))])
+			}
+			term := breaks[rng.Intn(len(breaks))]
+			if n == 1 && rng.Intn(2) == 0 {
+				term = ""
+			}
+			if term == "" && line.Len() == 0 {
+				break
+			}
+			if term == "\n" && line.Len() == 0 && len(terms) > 0 && terms[len(terms)-1] == "\r" {
+				// A \r followed by \n is one break.
+				term = "\v"
+			}
+			b.WriteString(line.String() + term)
+			lines = append(lines, line.String())
+			terms = append(terms, term)
+		}
+		text := b.String()
+
+		var ls LineSplitter
+		for j, split := range []SplitFunc{SplitUniversalLines, ls.Split} {
+			sc := New(&slowReader{1 + rng.Intn(5), strings.NewReader(text)})
+			sc.Split(split)
+			var got, gotTerms []string
+			for sc.Next() {
+				got = append(got, sc.Text())
+				gotTerms = append(gotTerms, ls.Terminator())
+				if start, end := sc.Span(); end.Offset-start.Offset != int64(len(sc.Bytes())) {
+					t.Fatalf("%d.%d: %q: span %v-%v of token %q", i, j, text, start, end, sc.Bytes())
+				}
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, lines) {
+				t.Fatalf("%d.%d: %q: got %q; want %q", i, j, text, got, lines)
+			}
+			if j == 1 && !slices.Equal(gotTerms, terms) {
+				t.Fatalf("%d: %q: got terminators %q; want %q", i, text, gotTerms, terms)
+			}
+		}
+	}
+}
+
+// Test that the lines returned by SplitRawLines put back together are
+// the input, and are the lines of SplitLines with their end-of-line
+// markers.
+func TestScanRawLines(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []byte("ab\r\n\xff\xe6")
+	for i := 0; i < 1000; i++ {
+		text := make([]byte, rng.Intn(200))
+		for j := range text {
+			if i%2 == 0 {
+				text[j] = alphabet[rng.Intn(len(alphabet))]
+			} else {
+				text[j] = byte(rng.Intn(256))
+			}
+		}
+
+		sc := New(&slowReader{1 + rng.Intn(10), bytes.NewReader(text)})
+		sc.Split(SplitRawLines)
+		var raw []byte
+		var lines []string
+		for sc.Next() {
+			line := sc.Bytes()
+			if len(line) == 0 {
+				t.Fatalf("%d: empty token", i)
+			}
+			if !bytes.HasSuffix(line, []byte("\n")) && len(raw)+len(line) != len(text) {
+				t.Fatalf("%d: token %q without newline before the end of input", i, line)
+			}
+			raw = append(raw, line...)
+
+			line = bytes.TrimSuffix(line, []byte("\n"))
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			lines = append(lines, string(line))
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, text) {
+			t.Fatalf("%d: got %q; want %q", i, raw, text)
+		}
+		if want := scanAll(string(text), SplitLines); !slices.Equal(lines, want) {
+			t.Fatalf("%d: stripped lines %q; want %q", i, lines, want)
+		}
+	}
+}
+
+// Test that the word splitters with other separators return the same data
+// as strings.FieldsFunc, even when runes are cut in two by reads.
+func TestScanWordsFunc(t *testing.T) {
+	t.Parallel()
+
+	isZeroWidthOrSpace := func(r rune) bool { return r == '\u200b' || unicode.IsSpace(r) }
+	tests := []struct {
+		name  string
+		split SplitFunc
+		isSep func(rune) bool
+	}{
+		{"ascii", SplitWordsASCII, func(r rune) bool { return strings.ContainsRune(" \t\n\v\f\r", r) }},
+		{"punct", SplitWordsPunct, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }},
+		{"func", SplitWordsFunc(isZeroWidthOrSpace), isZeroWidthOrSpace},
+	}
+	texts := append(wordScanTests, "a,b;c. d!", "x\u200by\u3000z", "«日本語»、¼ 「a」")
+	for _, test := range tests {
+		for n, text := range texts {
+			for _, max := range []int{1, 2, 100} {
+				sc := New(&slowReader{max, strings.NewReader(text)})
+				sc.Split(test.split)
+				var got []string
+				for sc.Next() {
+					got = append(got, sc.Text())
+				}
+				if err := sc.Err(); err != nil {
+					t.Fatal(err)
+				}
+				if want := strings.FieldsFunc(text, test.isSep); !slices.Equal(got, want) {
+					t.Errorf("%s %d: got %q; want %q", test.name, n, got, want)
+				}
+			}
+		}
+	}
+}